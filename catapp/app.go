@@ -6,11 +6,14 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/surkovvs/gocat/catapp/compstor"
+	"github.com/surkovvs/gocat/catapp/inject"
 	"github.com/surkovvs/gocat/catapp/interfaces"
+	"github.com/surkovvs/gocat/catmetrics"
 )
 
 var (
@@ -25,6 +28,7 @@ type (
 		errFlow       chan error
 		initRunCancel context.CancelFunc
 		initTimeout   *time.Duration
+		crashOnPanic  bool
 	}
 	shutdown struct {
 		ctx          context.Context
@@ -34,11 +38,16 @@ type (
 		exitCode     int
 	}
 	app struct {
-		execution execution
-		shutdown  shutdown
-		storage   compstor.CompsStorage
-		name      string
-		logger    interfaces.Logger
+		execution  execution
+		shutdown   shutdown
+		storage    compstor.CompsStorage
+		container  *inject.Container
+		validation validation
+		metrics    *catmetrics.Registry
+		name       string
+		logger     interfaces.Logger
+		// user provided logger without lifecycle prefix, passed to modules
+		modLogger interfaces.Logger
 	}
 )
 
@@ -57,7 +66,13 @@ func New(opts ...appOption) *app {
 			timeout:      nil,
 			exitCode:     0,
 		},
-		storage: compstor.NewCompsStorage(),
+		storage:   compstor.NewCompsStorage(),
+		container: inject.New(),
+		validation: validation{
+			mu:          &sync.Mutex{},
+			longRunning: make(map[string]struct{}),
+		},
+		metrics: catmetrics.NewRegistry(),
 		name:    "",
 		logger:  nil,
 	}
@@ -81,7 +96,9 @@ func (a *app) defaultSettingsCheckAndApply() {
 		a.name = `unnamed`
 	}
 
-	a.logger = newLogWrap(a.logger)
+	wrap := newLogWrap(a.logger)
+	a.modLogger = wrap.logger
+	a.logger = wrap
 
 	if a.shutdown.sigs == nil {
 		a.shutdown.sigs = defaultProvidedSigs
@@ -0,0 +1,80 @@
+package appctx
+
+import (
+	"context"
+
+	"github.com/surkovvs/gocat/catlog"
+)
+
+type Phase string
+
+const (
+	PhaseInit        Phase = `init`
+	PhaseRun         Phase = `run`
+	PhaseShutdown    Phase = `shutdown`
+	PhaseHealthcheck Phase = `healthcheck`
+)
+
+type ctxKey struct{}
+
+type values struct {
+	app    string
+	group  string
+	module string
+	phase  Phase
+	logger catlog.Logger
+}
+
+// With enriches ctx with module execution info, logger will be scoped
+// with the same fields as lifecycle logs of catapp.
+func With(ctx context.Context, app, group, module string, phase Phase, logger catlog.Logger) context.Context {
+	if logger == nil {
+		logger = catlog.Nop()
+	}
+	return context.WithValue(ctx, ctxKey{}, values{
+		app:    app,
+		group:  group,
+		module: module,
+		phase:  phase,
+		logger: catlog.With(logger,
+			`application`, app,
+			`group`, group,
+			`module`, module,
+			`phase`, string(phase)),
+	})
+}
+
+func get(ctx context.Context) (values, bool) {
+	v, ok := ctx.Value(ctxKey{}).(values)
+	return v, ok
+}
+
+func AppName(ctx context.Context) string {
+	v, _ := get(ctx)
+	return v.app
+}
+
+func GroupName(ctx context.Context) string {
+	v, _ := get(ctx)
+	return v.group
+}
+
+func ModuleName(ctx context.Context) string {
+	v, _ := get(ctx)
+	return v.module
+}
+
+func GetPhase(ctx context.Context) Phase {
+	v, _ := get(ctx)
+	return v.phase
+}
+
+// Logger returns logger scoped with application, group, module and phase,
+// if ctx was not passed by catapp, nop logger returned.
+func Logger(ctx context.Context) catlog.Logger {
+	v, ok := get(ctx)
+	if !ok {
+		return catlog.Nop()
+	}
+	return v.logger
+}
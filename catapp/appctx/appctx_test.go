@@ -0,0 +1,40 @@
+package appctx
+
+import (
+	"context"
+	"testing"
+)
+
+type recLogger struct {
+	args []any
+}
+
+func (l *recLogger) Debug(_ string, args ...any) { l.args = args }
+func (l *recLogger) Info(_ string, args ...any)  { l.args = args }
+func (l *recLogger) Warn(_ string, args ...any)  { l.args = args }
+func (l *recLogger) Error(_ string, args ...any) { l.args = args }
+
+func TestWith(t *testing.T) {
+	rec := &recLogger{}
+	ctx := With(context.Background(), "app", "group", "module", PhaseRun, rec)
+
+	if AppName(ctx) != "app" || GroupName(ctx) != "group" ||
+		ModuleName(ctx) != "module" || GetPhase(ctx) != PhaseRun {
+		t.Fatal("unexpected context values")
+	}
+
+	Logger(ctx).Info("msg", "key", "val")
+	want := []any{`application`, "app", `group`, "group", `module`, "module", `phase`, "run", "key", "val"}
+	if len(rec.args) != len(want) {
+		t.Fatalf("got %v, want %v", rec.args, want)
+	}
+	for i := range want {
+		if rec.args[i] != want[i] {
+			t.Fatalf("got %v, want %v", rec.args, want)
+		}
+	}
+
+	if Logger(context.Background()) == nil {
+		t.Fatal("nil logger for bare context")
+	}
+}
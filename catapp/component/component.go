@@ -73,6 +73,28 @@ func (c Comp) Name() string {
 	return c.name
 }
 
+func (c Comp) Object() any {
+	return c.object
+}
+
+// Capabilities returns names of implemented lifecycle interfaces
+func (c Comp) Capabilities() []string {
+	var caps []string
+	if c.IsInitializer() {
+		caps = append(caps, "init")
+	}
+	if c.IsRunner() {
+		caps = append(caps, "run")
+	}
+	if c.IsShutdowner() {
+		caps = append(caps, "shutdown")
+	}
+	if c.IsHealthchecker() {
+		caps = append(caps, "healthcheck")
+	}
+	return caps
+}
+
 // healthcheck crew
 
 func (c Comp) IsHealthchecker() bool {
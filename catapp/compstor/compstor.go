@@ -23,10 +23,20 @@ type CompsStorage struct {
 	groupCounter groupNum
 }
 
+// GroupMode defines how runners of the group are executed,
+// initializers are always processed sequentially.
+type GroupMode struct {
+	// all runners of the group are started together, group finishes when all of them finished
+	ConcurrentRun bool
+	// on runner failure context of its siblings is canceled, applyed for concurrent run only
+	StopSiblingsOnFailure bool
+}
+
 type SequentialGroup struct {
 	name  string
 	num   groupNum
 	comps []component.Comp
+	mode  GroupMode
 }
 
 func NewCompsStorage() CompsStorage {
@@ -78,6 +88,22 @@ func (cs *CompsStorage) AddGroup(groupName string) error {
 	return nil
 }
 
+func (cs *CompsStorage) SetGroupMode(groupName string, mode GroupMode) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	group, ok := cs.groups[groupName]
+	if !ok {
+		group = SequentialGroup{
+			name: groupName,
+			num:  cs.groupCounter,
+		}
+		cs.groupCounter++
+	}
+	group.mode = mode
+	cs.groups[groupName] = group
+}
+
 func (cs *CompsStorage) GetOrderedGroupList() []SequentialGroup {
 	cs.mu.Lock()
 	groupList := make([]SequentialGroup, 0, len(cs.groups))
@@ -113,6 +139,21 @@ func (cs *CompsStorage) GetUnsortedShutdowners() []component.Comp {
 	return compList
 }
 
+func (cs *CompsStorage) GetComponentGroupName(comp component.Comp) string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	num, ok := cs.comps[comp]
+	if !ok {
+		return ""
+	}
+	for name, group := range cs.groups {
+		if group.num == num {
+			return name
+		}
+	}
+	return ""
+}
+
 func (sg SequentialGroup) GetName() string {
 	return sg.name
 }
@@ -121,6 +162,10 @@ func (sg SequentialGroup) GetComponents() []component.Comp {
 	return sg.comps
 }
 
+func (sg SequentialGroup) GetMode() GroupMode {
+	return sg.mode
+}
+
 // // TODO:
 // func (cs *CompsStorage) GetComponentByName() {
 // 	panic("not implemented")
@@ -5,7 +5,9 @@ import (
 	"os"
 	"time"
 
+	"github.com/surkovvs/gocat/catapp/compstor"
 	"github.com/surkovvs/gocat/catapp/interfaces"
+	"github.com/surkovvs/gocat/catmetrics"
 )
 
 type appOption func(*app)
@@ -28,6 +30,40 @@ func WithInitTimeout(to time.Duration) appOption {
 	}
 }
 
+// WithCrashOnPanic disables recovering of modules panics.
+func WithCrashOnPanic() appOption {
+	return func(a *app) {
+		a.execution.crashOnPanic = true
+	}
+}
+
+// WithStrictValidation fails the start on modules validation warnings
+// and on modules rejected at registration.
+func WithStrictValidation() appOption {
+	return func(a *app) {
+		a.validation.strict = true
+	}
+}
+
+// WithLongRunningGroups marks groups, which runners work until shutdown, e.g.
+// servers and consumers. Validation warns about their runners without Shutdowner,
+// as such runners could not be stopped gracefully.
+func WithLongRunningGroups(groupNames ...string) appOption {
+	return func(a *app) {
+		for _, name := range groupNames {
+			a.validation.longRunning[name] = struct{}{}
+		}
+	}
+}
+
+// WithMetricsRegistry sets registry, which modules implementing
+// catmetrics.Collector are registered in, e.g. to share it between apps.
+func WithMetricsRegistry(reg *catmetrics.Registry) appOption {
+	return func(a *app) {
+		a.metrics = reg
+	}
+}
+
 func WithProvidedSigs(sigs ...os.Signal) appOption {
 	return func(a *app) {
 		a.shutdown.sigs = sigs
@@ -40,6 +76,23 @@ func WithShutdownTimeout(to time.Duration) appOption {
 	}
 }
 
+type groupOption func(*compstor.GroupMode)
+
+// WithConcurrentRun makes all runners of the group start together after
+// sequential initialization, group finishes when all its runners finished.
+func WithConcurrentRun() groupOption {
+	return func(gm *compstor.GroupMode) {
+		gm.ConcurrentRun = true
+	}
+}
+
+// WithSiblingsStopOnFailure cancels context of the group runners when one of them failed.
+func WithSiblingsStopOnFailure() groupOption {
+	return func(gm *compstor.GroupMode) {
+		gm.StopSiblingsOnFailure = true
+	}
+}
+
 type logWrap struct {
 	logger interfaces.Logger
 }
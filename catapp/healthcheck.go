@@ -0,0 +1,48 @@
+package catapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/surkovvs/gocat/catapp/appctx"
+	"github.com/surkovvs/gocat/catapp/component"
+)
+
+// Healthcheck calls modules implementing Healthchecker concurrently and joins
+// their errors, so the result could be checked with errors.Is for module errors
+// (e.g. catdb.ErrDegraded). nil means all modules are healthy.
+func (a *app) Healthcheck(ctx context.Context) error {
+	type check struct {
+		group  string
+		module component.Comp
+	}
+	var checks []check
+	for _, group := range a.storage.GetOrderedGroupList() {
+		for _, module := range group.GetComponents() {
+			if module.IsHealthchecker() {
+				checks = append(checks, check{group: group.GetName(), module: module})
+			}
+		}
+	}
+
+	errs := make([]error, len(checks))
+	wg := sync.WaitGroup{}
+	for i, chk := range checks {
+		wg.Add(1)
+		go func(i int, chk check) {
+			defer wg.Done()
+			err := a.safeCall(chk.module.Name(), appctx.PhaseHealthcheck, func() error {
+				return chk.module.Healthchecker().Get().Healthcheck(
+					a.moduleContext(ctx, chk.group, chk.module.Name(), appctx.PhaseHealthcheck))
+			})
+			if err != nil {
+				errs[i] = fmt.Errorf(`healthcheck of module %s, from group %s: %w`,
+					chk.module.Name(), chk.group, err)
+			}
+		}(i, chk)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,32 @@
+package catapp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/surkovvs/gocat/catapp/component"
+	"github.com/surkovvs/gocat/catapp/compstor"
+)
+
+var errUnhealthy = errors.New("unhealthy")
+
+type failingHealth struct{}
+
+func (failingHealth) Healthcheck(context.Context) error { return errUnhealthy }
+
+func TestHealthcheck(t *testing.T) {
+	a := &app{
+		storage: compstor.NewCompsStorage(),
+		logger:  newLogWrap(nil),
+	}
+	_ = a.storage.AddComponent("group", "ok", component.DefineComponent("ok", healthOnly{}))
+	if err := a.Healthcheck(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = a.storage.AddComponent("group", "failing", component.DefineComponent("failing", failingHealth{}))
+	if err := a.Healthcheck(context.Background()); !errors.Is(err, errUnhealthy) {
+		t.Fatalf("unhealthy error expected, got %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package inject
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNotFunction         = errors.New("constructor must be a function")
+	ErrBadSignature        = errors.New("constructor must return value or value and error")
+	ErrAlreadyProvided     = errors.New("type already provided")
+	ErrMissingDependency   = errors.New("missing dependency")
+	ErrAmbiguousDependency = errors.New("ambiguous dependency")
+	ErrDependencyCycle     = errors.New("dependency cycle")
+	ErrConstructorPanic    = errors.New("constructor panicked")
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+type state int
+
+const (
+	stateNew state = iota
+	stateVisiting
+	stateBuilt
+	stateFailed
+)
+
+// Provided is a value made by registered constructor.
+type Provided struct {
+	Group     string
+	Name      string
+	Value     any
+	DependsOn []string // names of provided values passed to constructor, supplied ones are omitted
+}
+
+type provider struct {
+	group    string
+	ctor     reflect.Value
+	out      reflect.Type
+	in       []reflect.Type
+	value    reflect.Value
+	state    state
+	supplied bool
+}
+
+// Container resolves constructors dependencies by types,
+// every type could be provided only once.
+type Container struct {
+	mu        *sync.Mutex
+	providers []*provider
+	byType    map[reflect.Type]*provider
+	provided  []Provided
+	resolved  bool
+	err       error
+}
+
+func New() *Container {
+	return &Container{
+		mu:     &sync.Mutex{},
+		byType: make(map[reflect.Type]*provider),
+	}
+}
+
+// Provide registers constructor, constructor parameters are dependencies,
+// it must return single value or value and error.
+func (c *Container) Provide(group string, ctor any) error {
+	ctorVal := reflect.ValueOf(ctor)
+	if ctorVal.Kind() != reflect.Func {
+		return fmt.Errorf("%w: got %T", ErrNotFunction, ctor)
+	}
+	ctorType := ctorVal.Type()
+	if ctorType.IsVariadic() {
+		return fmt.Errorf("%w: variadic constructor %s", ErrBadSignature, ctorType)
+	}
+	switch {
+	case ctorType.NumOut() == 1 && ctorType.Out(0) != errorType:
+	case ctorType.NumOut() == 2 && ctorType.Out(0) != errorType && ctorType.Out(1) == errorType:
+	default:
+		return fmt.Errorf("%w: %s", ErrBadSignature, ctorType)
+	}
+
+	in := make([]reflect.Type, 0, ctorType.NumIn())
+	for i := 0; i < ctorType.NumIn(); i++ {
+		in = append(in, ctorType.In(i))
+	}
+
+	return c.add(&provider{
+		group: group,
+		ctor:  ctorVal,
+		out:   ctorType.Out(0),
+		in:    in,
+	})
+}
+
+// Supply registers ready to use value, supplied values are dependencies only.
+func (c *Container) Supply(value any) error {
+	if value == nil {
+		return fmt.Errorf("%w: nil value supplied", ErrBadSignature)
+	}
+	val := reflect.ValueOf(value)
+	return c.add(&provider{
+		out:      val.Type(),
+		value:    val,
+		state:    stateBuilt,
+		supplied: true,
+	})
+}
+
+func (c *Container) add(p *provider) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.resolved {
+		return errors.New("container already resolved")
+	}
+	if _, ok := c.byType[p.out]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyProvided, p.out)
+	}
+	c.byType[p.out] = p
+	c.providers = append(c.providers, p)
+	return nil
+}
+
+// Resolve calls all registered constructors, result is ordered by dependencies,
+// each value goes after values it depends on.
+func (c *Container) Resolve() ([]Provided, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.resolved {
+		return c.provided, c.err
+	}
+	c.resolved = true
+
+	var errs []error
+	for _, p := range c.providers {
+		if err := c.build(p, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.err = errors.Join(errs...)
+	return c.provided, c.err
+}
+
+func (c *Container) build(p *provider, path []reflect.Type) error {
+	switch p.state {
+	case stateBuilt:
+		return nil
+	case stateFailed:
+		return nil // already reported
+	case stateVisiting:
+		return fmt.Errorf("%w: %s", ErrDependencyCycle, formatPath(append(path, p.out)))
+	}
+
+	p.state = stateVisiting
+	path = append(path, p.out)
+
+	args := make([]reflect.Value, 0, len(p.in))
+	var dependsOn []string
+	for _, depType := range p.in {
+		dep, err := c.lookup(depType)
+		if err != nil {
+			p.state = stateFailed
+			return fmt.Errorf("constructing %s: %w", p.out, err)
+		}
+		if err := c.build(dep, path); err != nil {
+			p.state = stateFailed
+			return err
+		}
+		if dep.state != stateBuilt {
+			p.state = stateFailed
+			return fmt.Errorf("constructing %s: dependency %s failed", p.out, dep.out)
+		}
+		args = append(args, dep.value)
+		if !dep.supplied {
+			dependsOn = append(dependsOn, typeName(dep.out))
+		}
+	}
+
+	out, err := p.call(args)
+	if err != nil {
+		p.state = stateFailed
+		return err
+	}
+	if len(out) == 2 && !out[1].IsNil() {
+		p.state = stateFailed
+		return fmt.Errorf("constructing %s: %w", p.out, out[1].Interface().(error))
+	}
+	p.value = out[0]
+	p.state = stateBuilt
+
+	c.provided = append(c.provided, Provided{
+		Group:     p.group,
+		Name:      typeName(p.out),
+		Value:     p.value.Interface(),
+		DependsOn: dependsOn,
+	})
+	return nil
+}
+
+// call calls constructor, panic is converted to error with stack,
+// so it does not crash the application
+func (p *provider) call(args []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("constructing %s: %w: %v\n%s", p.out, ErrConstructorPanic, r, debug.Stack())
+		}
+	}()
+	return p.ctor.Call(args), nil
+}
+
+// typeName returns type name qualified with package path, so
+// same named types of different packages are distinguished
+func typeName(t reflect.Type) string {
+	switch {
+	case t.Kind() == reflect.Pointer:
+		return "*" + typeName(t.Elem())
+	case t.Name() != "" && t.PkgPath() != "":
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
+// lookup finds provider by exact type, for interfaces single implementation is suitable.
+func (c *Container) lookup(t reflect.Type) (*provider, error) {
+	if p, ok := c.byType[t]; ok {
+		return p, nil
+	}
+	if t.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("%w: %s", ErrMissingDependency, t)
+	}
+
+	var candidates []*provider
+	for _, p := range c.providers {
+		if p.out.Implements(t) {
+			candidates = append(candidates, p)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrMissingDependency, t)
+	case 1:
+		return candidates[0], nil
+	default:
+		names := make([]string, 0, len(candidates))
+		for _, p := range candidates {
+			names = append(names, p.out.String())
+		}
+		return nil, fmt.Errorf("%w: %s implemented by %s",
+			ErrAmbiguousDependency, t, strings.Join(names, ", "))
+	}
+}
+
+// Invoke resolves container if needed and calls fn with its dependencies,
+// fn could return error.
+func (c *Container) Invoke(fn any) error {
+	fnVal := reflect.ValueOf(fn)
+	if fnVal.Kind() != reflect.Func {
+		return fmt.Errorf("%w: got %T", ErrNotFunction, fn)
+	}
+	if _, err := c.Resolve(); err != nil {
+		return err
+	}
+
+	fnType := fnVal.Type()
+	args := make([]reflect.Value, 0, fnType.NumIn())
+	c.mu.Lock()
+	for i := 0; i < fnType.NumIn(); i++ {
+		dep, err := c.lookup(fnType.In(i))
+		if err != nil {
+			c.mu.Unlock()
+			return fmt.Errorf("invoking %s: %w", fnType, err)
+		}
+		args = append(args, dep.value)
+	}
+	c.mu.Unlock()
+
+	for _, out := range fnVal.Call(args) {
+		if out.Type() == errorType && !out.IsNil() {
+			return out.Interface().(error)
+		}
+	}
+	return nil
+}
+
+func formatPath(path []reflect.Type) string {
+	names := make([]string, 0, len(path))
+	for _, t := range path {
+		names = append(names, t.String())
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,97 @@
+package inject
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type (
+	config struct{ dsn string }
+	pool   struct{ cfg *config }
+	repo   struct{ pool *pool }
+	svc    struct{ repo Repository }
+)
+
+type Repository interface {
+	Init(ctx context.Context) error
+}
+
+func (r *repo) Init(context.Context) error { return nil }
+
+func TestResolveOrder(t *testing.T) {
+	c := New()
+	// registration order is reversed on purpose
+	if err := c.Provide("svc", func(r Repository) *svc { return &svc{repo: r} }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Provide("db", func(p *pool) (*repo, error) { return &repo{pool: p}, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Provide("db", func(cfg *config) *pool { return &pool{cfg: cfg} }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Supply(&config{dsn: "dsn"}); err != nil {
+		t.Fatal(err)
+	}
+
+	provided, err := c.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range provided {
+		names = append(names, p.Name)
+	}
+	const pkg = "github.com/surkovvs/gocat/catapp/inject"
+	want := []string{"*" + pkg + ".pool", "*" + pkg + ".repo", "*" + pkg + ".svc"}
+	if len(names) != len(want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("got %v, want %v", names, want)
+		}
+	}
+
+	if err := c.Invoke(func(s *svc) {
+		if s.repo.(*repo).pool.cfg.dsn != "dsn" {
+			t.Error("dependency was not passed")
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	c := New()
+	_ = c.Provide("", func(p *pool) *repo { return &repo{pool: p} })
+	if _, err := c.Resolve(); !errors.Is(err, ErrMissingDependency) {
+		t.Fatalf("expected missing dependency, got %v", err)
+	}
+
+	c = New()
+	_ = c.Provide("", func(*repo) *pool { return nil })
+	_ = c.Provide("", func(*pool) *repo { return nil })
+	if _, err := c.Resolve(); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected cycle, got %v", err)
+	}
+
+	c = New()
+	if err := c.Provide("", func() {}); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("expected bad signature, got %v", err)
+	}
+	_ = c.Provide("", func() *pool { return nil })
+	if err := c.Provide("", func() *pool { return nil }); !errors.Is(err, ErrAlreadyProvided) {
+		t.Fatalf("expected already provided, got %v", err)
+	}
+}
+
+func TestResolvePanic(t *testing.T) {
+	c := New()
+	_ = c.Provide("", func() *pool { panic("boom") })
+	_ = c.Provide("", func(p *pool) *repo { return &repo{pool: p} })
+	if _, err := c.Resolve(); !errors.Is(err, ErrConstructorPanic) {
+		t.Fatalf("expected constructor panic, got %v", err)
+	}
+}
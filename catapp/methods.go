@@ -4,11 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"reflect"
 	"sync"
 
+	"github.com/surkovvs/gocat/catapp/appctx"
 	"github.com/surkovvs/gocat/catapp/component"
 	"github.com/surkovvs/gocat/catapp/compstor"
+	"github.com/surkovvs/gocat/catmetrics"
 )
 
 func (a *app) Start(ctx context.Context) {
@@ -27,6 +28,22 @@ func (a *app) Start(ctx context.Context) {
 		initCtx = initRunCtx
 	}
 
+	if err := a.resolveProvided(); err != nil {
+		a.logger.Error(`dependencies resolving`,
+			"application", a.name,
+			"error", err)
+		a.abortStart()
+		return
+	}
+
+	if err := a.validate(); err != nil {
+		a.logger.Error(`modules validation`,
+			"application", a.name,
+			"error", err)
+		a.abortStart()
+		return
+	}
+
 	group, err := a.storage.GetGroupByName(PrivelegedGroup)
 	if err != nil {
 		if errors.Is(err, compstor.ErrGroupNotFound) {
@@ -63,6 +80,13 @@ func (a *app) Start(ctx context.Context) {
 	<-a.shutdown.shutdownDone
 }
 
+// abortStart finishes the app without modules execution
+func (a *app) abortStart() {
+	a.shutdown.exitCode = 1
+	close(a.execution.done)
+	<-a.shutdown.shutdownDone
+}
+
 func (a *app) processInitializers(ctx context.Context, group compstor.SequentialGroup) {
 	for _, module := range group.GetComponents() {
 		if module.Initializer().TrySetInProcess() {
@@ -71,7 +95,10 @@ func (a *app) processInitializers(ctx context.Context, group compstor.Sequential
 				`group`, group.GetName(),
 				`module`, module.Name())
 
-			if err := module.Initializer().Get().Init(ctx); err != nil {
+			if err := a.safeCall(module.Name(), appctx.PhaseInit, func() error {
+				return module.Initializer().Get().Init(
+					a.moduleContext(ctx, group.GetName(), module.Name(), appctx.PhaseInit))
+			}); err != nil {
 				a.execution.errFlow <- fmt.Errorf(
 					`initializing module %s, from group %s, failed: %w`,
 					module.Name(), group.GetName(), err)
@@ -85,25 +112,61 @@ func (a *app) processInitializers(ctx context.Context, group compstor.Sequential
 }
 
 func (a *app) processRunners(ctx context.Context, group compstor.SequentialGroup) {
+	if group.GetMode().ConcurrentRun {
+		a.processRunnersConcurrently(ctx, group)
+		return
+	}
 	for _, module := range group.GetComponents() {
-		if (module.Initializer().IsDone() || !module.IsInitializer()) &&
-			module.Runner().TrySetInProcess() {
-			a.logger.Debug(`Module running`,
-				`application`, a.name,
-				`group`, group.GetName(),
-				`module`, module.Name())
+		if !a.runModule(ctx, group, module) {
+			return
+		}
+	}
+}
 
-			if err := module.Runner().Get().Run(ctx); err != nil {
-				a.execution.errFlow <- fmt.Errorf(
-					`running module %s, from group %s, failed: %w`,
-					module.Name(), group.GetName(), err)
+func (a *app) processRunnersConcurrently(ctx context.Context, group compstor.SequentialGroup) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-				module.Runner().SetFailed()
-				return
+	wg := sync.WaitGroup{}
+	for _, module := range group.GetComponents() {
+		wg.Add(1)
+		go func(module component.Comp) {
+			defer wg.Done()
+			if !a.runModule(runCtx, group, module) && group.GetMode().StopSiblingsOnFailure {
+				a.logger.Debug(`Siblings of failed module stopping`,
+					`application`, a.name,
+					`group`, group.GetName(),
+					`module`, module.Name())
+				cancel()
 			}
-			module.Runner().SetDone()
+		}(module)
+	}
+	wg.Wait()
+}
+
+// runModule returns false if module running failed
+func (a *app) runModule(ctx context.Context, group compstor.SequentialGroup, module component.Comp) bool {
+	if (module.Initializer().IsDone() || !module.IsInitializer()) &&
+		module.Runner().TrySetInProcess() {
+		a.logger.Debug(`Module running`,
+			`application`, a.name,
+			`group`, group.GetName(),
+			`module`, module.Name())
+
+		if err := a.safeCall(module.Name(), appctx.PhaseRun, func() error {
+			return module.Runner().Get().Run(
+				a.moduleContext(ctx, group.GetName(), module.Name(), appctx.PhaseRun))
+		}); err != nil {
+			a.execution.errFlow <- fmt.Errorf(
+				`running module %s, from group %s, failed: %w`,
+				module.Name(), group.GetName(), err)
+
+			module.Runner().SetFailed()
+			return false
 		}
+		module.Runner().SetDone()
 	}
+	return true
 }
 
 func (a *app) processShutdowners(ctx context.Context, group compstor.SequentialGroup) {
@@ -114,7 +177,10 @@ func (a *app) processShutdowners(ctx context.Context, group compstor.SequentialG
 				`group`, group.GetName(),
 				`module`, module.Name())
 
-			if err := module.Shutdowner().Get().Shutdown(ctx); err != nil {
+			if err := a.safeCall(module.Name(), appctx.PhaseShutdown, func() error {
+				return module.Shutdowner().Get().Shutdown(
+					a.moduleContext(ctx, group.GetName(), module.Name(), appctx.PhaseShutdown))
+			}); err != nil {
 				a.execution.errFlow <- fmt.Errorf(
 					`shutdown module %s, from group %s, failed: %w`,
 					module.Name(), group.GetName(), err)
@@ -127,15 +193,31 @@ func (a *app) processShutdowners(ctx context.Context, group compstor.SequentialG
 	}
 }
 
+func (a *app) moduleContext(ctx context.Context, groupName, moduleName string, phase appctx.Phase) context.Context {
+	return appctx.With(ctx, a.name, groupName, moduleName, phase, a.modLogger)
+}
+
+// DefineGroup sets execution mode for the group, group will be created if not exists.
+func (a *app) DefineGroup(groupName string, opts ...groupOption) {
+	var mode compstor.GroupMode
+	for _, opt := range opts {
+		opt(&mode)
+	}
+	a.storage.SetGroupMode(groupName, mode)
+}
+
 func (a *app) AddModuleToGroup(groupName, moduleName string, module any) {
 	comp := component.DefineComponent(moduleName, module)
 	if !comp.IsValid() {
+		reason := invalidModuleReason(module)
 		a.logger.Error(`module addition`,
 			"application", a.name,
 			`group`, groupName,
 			`module`, moduleName,
-			`unapplyed`, reflect.ValueOf(module).Type().Name(),
-			`error`, "module does not implement valid methods")
+			`unapplyed`, fmt.Sprintf("%T", module),
+			`error`, reason)
+		a.registrationFailed(fmt.Errorf(`module %s, from group %s, rejected: %s`,
+			moduleName, groupName, reason))
 		return
 	}
 	if err := a.storage.AddComponent(groupName, moduleName, comp); err != nil {
@@ -143,7 +225,20 @@ func (a *app) AddModuleToGroup(groupName, moduleName string, module any) {
 			"application", a.name,
 			`group`, groupName,
 			`module`, moduleName,
-			`unapplyed`, reflect.ValueOf(module).Type().Name(),
+			`unapplyed`, fmt.Sprintf("%T", module),
 			`error`, err)
+		a.registrationFailed(fmt.Errorf(`module %s, from group %s, rejected: %w`,
+			moduleName, groupName, err))
+		return
+	}
+	if collector, ok := module.(catmetrics.Collector); ok {
+		a.metrics.Register(collector,
+			catmetrics.Label{Name: "group", Value: groupName},
+			catmetrics.Label{Name: "module", Value: moduleName})
 	}
 }
+
+// Metrics returns registry with metrics of modules, serve it with Handler.
+func (a *app) Metrics() *catmetrics.Registry {
+	return a.metrics
+}
@@ -0,0 +1,43 @@
+package catapp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/surkovvs/gocat/catmetrics"
+)
+
+type collectingModule struct{ conns int }
+
+func (*collectingModule) Init(context.Context) error { return nil }
+
+func (m *collectingModule) Collect(w *catmetrics.Writer) {
+	w.Gauge("conns", "Open connections.", float64(m.conns))
+}
+
+type collectorOnly struct{}
+
+func (collectorOnly) Collect(w *catmetrics.Writer) {
+	w.Gauge("conns", "Open connections.", 1)
+}
+
+func TestAddModuleRegistersCollector(t *testing.T) {
+	a := newTestApp()
+	a.AddModuleToGroup("storage", "orders", &collectingModule{conns: 2})
+	a.AddModuleToGroup("storage", "users", &collectingModule{conns: 3})
+	a.AddModuleToGroup("storage", "stats", collectorOnly{}) // rejected, no lifecycle methods
+
+	sb := strings.Builder{}
+	if err := a.Metrics().WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP conns Open connections.
+# TYPE conns gauge
+conns{group="storage",module="orders"} 2
+conns{group="storage",module="users"} 3
+`
+	if sb.String() != expected {
+		t.Fatalf("unexpected output:\n%s", sb.String())
+	}
+}
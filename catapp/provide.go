@@ -0,0 +1,91 @@
+package catapp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/surkovvs/gocat/catapp/component"
+)
+
+// ErrCrossGroupDependency is returned on Start, if provided module depends on
+// module of other group, groups are started concurrently, so init order of
+// such modules is not guaranteed.
+var ErrCrossGroupDependency = errors.New("provided module depends on module of other group")
+
+// Provide registers constructors for dependency injection, constructors
+// parameters are resolved by types on Start. Constructed values implementing
+// lifecycle interfaces are added to the group in order of dependencies,
+// modules could depend only on modules of the same group.
+func (a *app) Provide(groupName string, constructors ...any) {
+	for _, ctor := range constructors {
+		if err := a.container.Provide(groupName, ctor); err != nil {
+			a.logger.Error(`constructor addition`,
+				"application", a.name,
+				`group`, groupName,
+				`error`, err)
+			a.registrationFailed(fmt.Errorf(`constructor %T, from group %s, rejected: %w`,
+				ctor, groupName, err))
+		}
+	}
+}
+
+// Supply registers ready values as dependencies for provided constructors,
+// supplied values are not added as modules.
+func (a *app) Supply(values ...any) {
+	for _, value := range values {
+		if err := a.container.Supply(value); err != nil {
+			a.logger.Error(`value supply`,
+				"application", a.name,
+				`error`, err)
+			a.registrationFailed(fmt.Errorf(`supplied value %T rejected: %w`, value, err))
+		}
+	}
+}
+
+// Invoke calls fn with resolved dependencies, could be used before Start
+// to get constructed values.
+func (a *app) Invoke(fn any) error {
+	return a.container.Invoke(fn)
+}
+
+func (a *app) resolveProvided() error {
+	provided, err := a.container.Resolve()
+	if err != nil {
+		return err
+	}
+
+	moduleGroups := make(map[string]string, len(provided))
+	for _, p := range provided {
+		if component.DefineComponent(p.Name, p.Value).IsValid() {
+			moduleGroups[p.Name] = p.Group
+		}
+	}
+	var errs []error
+	for _, p := range provided {
+		group, isModule := moduleGroups[p.Name]
+		if !isModule {
+			continue
+		}
+		for _, dep := range p.DependsOn {
+			if depGroup, ok := moduleGroups[dep]; ok && depGroup != group {
+				errs = append(errs, fmt.Errorf(`%w: %s from group %s depends on %s from group %s`,
+					ErrCrossGroupDependency, p.Name, group, dep, depGroup))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, p := range provided {
+		if _, isModule := moduleGroups[p.Name]; !isModule {
+			continue
+		}
+		a.logger.Debug(`provided module registration`,
+			"application", a.name,
+			`group`, p.Group,
+			`module`, p.Name)
+		a.AddModuleToGroup(p.Group, p.Name, p.Value)
+	}
+	return nil
+}
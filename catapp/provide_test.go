@@ -0,0 +1,59 @@
+package catapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/surkovvs/gocat/catapp/compstor"
+	"github.com/surkovvs/gocat/catapp/inject"
+	"github.com/surkovvs/gocat/catmetrics"
+)
+
+type (
+	dbModule   struct{}
+	repoModule struct{ db *dbModule }
+)
+
+func (*dbModule) Init(context.Context) error   { return nil }
+func (*repoModule) Init(context.Context) error { return nil }
+
+func newTestApp() *app {
+	return &app{
+		storage:    compstor.NewCompsStorage(),
+		logger:     newLogWrap(nil),
+		container:  inject.New(),
+		validation: validation{mu: &sync.Mutex{}},
+		metrics:    catmetrics.NewRegistry(),
+	}
+}
+
+func TestResolveProvidedGroups(t *testing.T) {
+	a := newTestApp()
+	a.Provide("db", func() *dbModule { return &dbModule{} })
+	a.Provide("repo", func(db *dbModule) *repoModule { return &repoModule{db: db} })
+	if err := a.resolveProvided(); !errors.Is(err, ErrCrossGroupDependency) {
+		t.Fatalf("ErrCrossGroupDependency expected, got %v", err)
+	}
+
+	a = newTestApp()
+	a.Provide("db", func() *dbModule { return &dbModule{} })
+	a.Provide("db", func(db *dbModule) *repoModule { return &repoModule{db: db} })
+	if err := a.resolveProvided(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProvideRejected(t *testing.T) {
+	a := newTestApp()
+	a.validation.strict = true
+	a.Provide("db", func() {})
+	a.Supply(nil)
+	if err := a.validate(); !errors.Is(err, inject.ErrBadSignature) {
+		t.Fatalf("ErrBadSignature expected, got %v", err)
+	}
+	if len(a.validation.regErrs) != 2 {
+		t.Fatalf("expected 2 registration errors, got %v", a.validation.regErrs)
+	}
+}
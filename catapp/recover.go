@@ -0,0 +1,40 @@
+package catapp
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/surkovvs/gocat/catapp/appctx"
+)
+
+// PanicError is returned instead of module panic, could be caught with errors.As.
+type PanicError struct {
+	Module string
+	Phase  appctx.Phase
+	Value  any
+	Stack  []byte
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s phase of module %s: %v\n%s",
+		pe.Phase, pe.Module, pe.Value, pe.Stack)
+}
+
+// safeCall converts panic in module method to PanicError,
+// if crash on panic option applied, panic goes further.
+func (a *app) safeCall(moduleName string, phase appctx.Phase, call func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if a.execution.crashOnPanic {
+				panic(r)
+			}
+			err = &PanicError{
+				Module: moduleName,
+				Phase:  phase,
+				Value:  r,
+				Stack:  debug.Stack(),
+			}
+		}
+	}()
+	return call()
+}
@@ -0,0 +1,32 @@
+package catapp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/surkovvs/gocat/catapp/appctx"
+)
+
+func TestSafeCall(t *testing.T) {
+	a := &app{}
+	err := a.safeCall("module", appctx.PhaseRun, func() error {
+		panic("boom")
+	})
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected panic error, got %v", err)
+	}
+	if pe.Value != "boom" || pe.Module != "module" || pe.Phase != appctx.PhaseRun || len(pe.Stack) == 0 {
+		t.Fatalf("unexpected panic error: %+v", pe)
+	}
+
+	a.execution.crashOnPanic = true
+	defer func() {
+		if recover() == nil {
+			t.Fatal("panic expected")
+		}
+	}()
+	_ = a.safeCall("module", appctx.PhaseRun, func() error {
+		panic("boom")
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/surkovvs/gocat/catapp/appctx"
 	"github.com/surkovvs/gocat/catapp/component"
 )
 
@@ -23,7 +24,10 @@ func (a *app) gracefulShutdown() {
 			defer wg.Done()
 
 			if module.Shutdowner().TrySetInProcess() {
-				if err := module.Shutdowner().Get().Shutdown(ctx); err != nil {
+				if err := a.safeCall(module.Name(), appctx.PhaseShutdown, func() error {
+					return module.Shutdowner().Get().Shutdown(a.moduleContext(ctx,
+						a.storage.GetComponentGroupName(module), module.Name(), appctx.PhaseShutdown))
+				}); err != nil {
 					a.execution.errFlow <- fmt.Errorf(
 						`shutdown module "%s", failed: %w`,
 						module.Name(), err)
@@ -0,0 +1,158 @@
+package catapp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/surkovvs/gocat/catapp/component"
+	"github.com/surkovvs/gocat/catapp/compstor"
+	"github.com/surkovvs/gocat/catapp/interfaces"
+)
+
+var ErrValidationFailed = errors.New("modules validation failed in strict mode")
+
+var lifecycleIfaces = []reflect.Type{
+	reflect.TypeOf((*interfaces.Initializer)(nil)).Elem(),
+	reflect.TypeOf((*interfaces.Runner)(nil)).Elem(),
+	reflect.TypeOf((*interfaces.Shutdowner)(nil)).Elem(),
+	reflect.TypeOf((*interfaces.Healthchecker)(nil)).Elem(),
+}
+
+type validation struct {
+	mu          *sync.Mutex
+	strict      bool
+	longRunning map[string]struct{} // groups, which runners work until shutdown
+	regErrs     []error
+}
+
+func (a *app) registrationFailed(err error) {
+	a.validation.mu.Lock()
+	a.validation.regErrs = append(a.validation.regErrs, err)
+	a.validation.mu.Unlock()
+}
+
+// invalidModuleReason explains why module has no lifecycle methods,
+// the common case is methods defined on pointer receiver with value passed.
+func invalidModuleReason(module any) string {
+	t := reflect.TypeOf(module)
+	if t == nil {
+		return "module is nil"
+	}
+	if t.Kind() != reflect.Pointer {
+		for _, iface := range lifecycleIfaces {
+			if reflect.PointerTo(t).Implements(iface) {
+				return fmt.Sprintf("methods are defined on pointer receiver, pass *%s instead of %s", t, t)
+			}
+		}
+	}
+	return "module does not implement valid methods"
+}
+
+// unreachableMethods returns lifecycle interfaces implemented by pointer
+// of passed value, but not by value itself.
+func unreachableMethods(module any) []string {
+	t := reflect.TypeOf(module)
+	if t == nil || t.Kind() == reflect.Pointer {
+		return nil
+	}
+	var names []string
+	for _, iface := range lifecycleIfaces {
+		if !t.Implements(iface) && reflect.PointerTo(t).Implements(iface) {
+			names = append(names, iface.Name())
+		}
+	}
+	return names
+}
+
+// validate logs startup report and warnings about likely mistakes,
+// in strict mode warnings and registration errors fail the start.
+func (a *app) validate() error {
+	groups := a.storage.GetOrderedGroupList()
+
+	var warns []string
+	names := make(map[string][]string)
+	for _, group := range groups {
+		for _, module := range group.GetComponents() {
+			names[module.Name()] = append(names[module.Name()], group.GetName())
+			warns = append(warns, a.moduleWarnings(group, module)...)
+		}
+	}
+	for name, groupNames := range names {
+		if len(groupNames) > 1 {
+			warns = append(warns, fmt.Sprintf("module name %s is used %d times, in groups: %s",
+				name, len(groupNames), strings.Join(groupNames, ", ")))
+		}
+	}
+
+	a.logger.Debug(`Startup report`+"\n"+startupReport(groups),
+		`application`, a.name)
+
+	for _, warn := range warns {
+		a.logger.Warn(`module validation`,
+			`application`, a.name,
+			`warning`, warn)
+	}
+
+	a.validation.mu.Lock()
+	regErrs := a.validation.regErrs
+	a.validation.mu.Unlock()
+
+	if !a.validation.strict || (len(warns) == 0 && len(regErrs) == 0) {
+		return nil
+	}
+	errs := make([]error, 0, len(warns)+len(regErrs)+1)
+	errs = append(errs, ErrValidationFailed)
+	errs = append(errs, regErrs...)
+	for _, warn := range warns {
+		errs = append(errs, errors.New(warn))
+	}
+	return errors.Join(errs...)
+}
+
+func (a *app) moduleWarnings(group compstor.SequentialGroup, module component.Comp) []string {
+	var warns []string
+	if module.IsHealthchecker() &&
+		!module.IsInitializer() && !module.IsRunner() && !module.IsShutdowner() {
+		warns = append(warns, fmt.Sprintf("module %s from group %s implements Healthchecker only, it takes no part in lifecycle",
+			module.Name(), group.GetName()))
+	}
+	_, longRunning := a.validation.longRunning[group.GetName()]
+	if longRunning && module.IsRunner() && !module.IsShutdowner() {
+		warns = append(warns, fmt.Sprintf("module %s from group %s is Runner without Shutdowner in long running group, it could not be stopped on graceful shutdown",
+			module.Name(), group.GetName()))
+	}
+	if methods := unreachableMethods(module.Object()); len(methods) != 0 {
+		warns = append(warns, fmt.Sprintf("module %s from group %s passed by value, %s implemented on pointer receiver will not be called",
+			module.Name(), group.GetName(), strings.Join(methods, ", ")))
+	}
+	return warns
+}
+
+func startupReport(groups []compstor.SequentialGroup) string {
+	sb := &strings.Builder{}
+	tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tMODE\tMODULE\tTYPE\tCAPABILITIES")
+	for _, group := range groups {
+		mode := "sequential"
+		if group.GetMode().ConcurrentRun {
+			mode = "concurrent"
+		}
+		if len(group.GetComponents()) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\n", group.GetName(), mode)
+		}
+		for _, module := range group.GetComponents() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				group.GetName(),
+				mode,
+				module.Name(),
+				reflect.TypeOf(module.Object()),
+				strings.Join(module.Capabilities(), ","))
+		}
+	}
+	_ = tw.Flush()
+	return sb.String()
+}
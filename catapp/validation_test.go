@@ -0,0 +1,71 @@
+package catapp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/surkovvs/gocat/catapp/component"
+	"github.com/surkovvs/gocat/catapp/compstor"
+)
+
+type ptrRunner struct{}
+
+func (*ptrRunner) Run(context.Context) error { return nil }
+
+type finiteRunner struct{ done bool }
+
+func (r *finiteRunner) Run(context.Context) error {
+	r.done = true
+	return nil
+}
+
+type healthOnly struct{}
+
+func (healthOnly) Healthcheck(context.Context) error { return nil }
+
+func TestInvalidModuleReason(t *testing.T) {
+	if reason := invalidModuleReason(ptrRunner{}); !strings.Contains(reason, "pointer receiver") {
+		t.Fatalf("unexpected reason: %s", reason)
+	}
+	if reason := invalidModuleReason(nil); reason != "module is nil" {
+		t.Fatalf("unexpected reason: %s", reason)
+	}
+}
+
+func TestValidateStrict(t *testing.T) {
+	a := &app{
+		storage: compstor.NewCompsStorage(),
+		logger:  newLogWrap(nil),
+		validation: validation{
+			mu:          &sync.Mutex{},
+			strict:      true,
+			longRunning: map[string]struct{}{"group": {}},
+		},
+	}
+	_ = a.storage.AddComponent("group", "health", component.DefineComponent("health", healthOnly{}))
+	_ = a.storage.AddComponent("group", "runner", component.DefineComponent("runner", &ptrRunner{}))
+
+	err := a.validate()
+	if err == nil {
+		t.Fatal("validation error expected")
+	}
+	for _, want := range []string{"Healthchecker only", "Runner without Shutdowner"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+
+	if err := a.storage.AddComponent("finite", "job", component.DefineComponent("job", &finiteRunner{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.validate(); strings.Contains(err.Error(), "module job") {
+		t.Fatalf("runner of finite group is reported: %v", err)
+	}
+
+	report := startupReport(a.storage.GetOrderedGroupList())
+	if !strings.Contains(report, "*catapp.ptrRunner") || !strings.Contains(report, "healthcheck") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+}
@@ -2,10 +2,12 @@ package catcfg
 
 import (
 	"fmt"
+	"os"
+	"reflect"
 
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 	"github.com/surkovvs/gocat/catdb"
+	"github.com/surkovvs/gocat/catkafka"
 	"github.com/surkovvs/gocat/catlog"
 )
 
@@ -13,22 +15,193 @@ type Config struct {
 	Logger           catlog.Logger `mapstructure:"-"`
 	catlog.ConfigLog `mapstructure:"Logger"`
 	catdb.ConfigDB   `mapstructure:"Database"`
-	Kafka            map[string]kafka.ConfigMap `mapstructure:"Kafka"` // `mapstructure:"-"`
+	Kafka            map[string]catkafka.ConfigKafka `mapstructure:"Kafka"`
+
+	meta *loadMeta
+}
+
+// Loader loads config from sources, each load uses its own viper
+// instances, so loaders are independent from each other.
+type Loader struct {
+	sources        []Source
+	envPrefix      string
+	environ        func() []string
+	skipValidation bool
+	resolvers      map[string]SecretResolver
+	flags          *pflag.FlagSet
 }
 
-func ParseFile(path string) (*Config, error) {
-	var cfg Config
-	viper.SetConfigFile(path)
-	if err := viper.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf(`read config: %w`, err)
+type loaderOption func(*Loader)
+
+// WithSources adds sources, values of the later source win.
+func WithSources(sources ...Source) loaderOption {
+	return func(l *Loader) {
+		l.sources = append(l.sources, sources...)
 	}
+}
 
-	viper.SetOptions(viper.KeyDelimiter("|"))
+// WithEnvPrefix enables overriding of sources values with environment
+// variables started with prefix, environment wins over all sources.
+func WithEnvPrefix(prefix string) loaderOption {
+	return func(l *Loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithFlags loads files set with --config after other sources and applies
+// flags bound with BindFlags or BindFlagPath over environment.
+func WithFlags(fs *pflag.FlagSet) loaderOption {
+	return func(l *Loader) {
+		l.flags = fs
+	}
+}
+
+// WithoutValidation disables config validation on load.
+func WithoutValidation() loaderOption {
+	return func(l *Loader) {
+		l.skipValidation = true
+	}
+}
 
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf(`unmarshal config: %w`, err)
+// WithSecretResolvers adds resolvers of secret references, resolver
+// replaces already registered one with the same scheme. File and env
+// resolvers are registered by default.
+func WithSecretResolvers(resolvers ...SecretResolver) loaderOption {
+	return func(l *Loader) {
+		for _, resolver := range resolvers {
+			l.resolvers[resolver.Scheme()] = resolver
+		}
+	}
+}
+
+func NewLoader(opts ...loaderOption) *Loader {
+	l := &Loader{
+		environ:   os.Environ,
+		resolvers: defaultResolvers(),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *Loader) Load() (*Config, error) {
+	return loadInto[Config](l)
+}
+
+// Load decodes config into user type, which embeds Config with squash tag:
+//
+//	type MyConfig struct {
+//		catcfg.Config `mapstructure:",squash"`
+//		Service       ServiceConfig `mapstructure:"Service"`
+//	}
+//
+// The same sources, environment and validation logic are applied to the whole
+// type, if type implements ConfigValidator, it is called after gocat sections checks.
+func Load[T any](opts ...loaderOption) (*T, error) {
+	return loadInto[T](NewLoader(opts...))
+}
+
+func loadInto[T any](l *Loader) (*T, error) {
+	target := new(T)
+	embedded, ok := any(target).(interface{ base() *Config })
+	if !ok {
+		return nil, fmt.Errorf(`config type %T does not embed catcfg.Config`, *target)
+	}
+	if err := checkSquashed(reflect.TypeOf(target).Elem()); err != nil {
+		return nil, err
 	}
-	return &cfg, nil
+
+	sources, err := l.allSources()
+	if err != nil {
+		return nil, err
+	}
+	settings, origins, err := loadSources(sources, reflect.TypeOf(target).Elem())
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := resolveSecrets(settings, l.resolvers)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decodeSettings(settings, target); err != nil {
+		return nil, err
+	}
+	cfg := embedded.base()
+	cfg.meta = &loadMeta{
+		settings: settings,
+		origins:  origins,
+		secrets:  secrets,
+	}
+
+	if !l.skipValidation {
+		v := &Validation{}
+		cfg.validate(v)
+		if hook, ok := any(target).(ConfigValidator); ok {
+			hook.ValidateConfig(v)
+		}
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+// checkSquashed verifies that directly embedded Config is decoded from the top level
+func checkSquashed(t reflect.Type) error {
+	configType := reflect.TypeOf(Config{})
+	if t == configType {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.Anonymous || (field.Type != configType && field.Type != reflect.PointerTo(configType)) {
+			continue
+		}
+		if field.Type.Kind() == reflect.Pointer {
+			return fmt.Errorf(`config type %s must embed catcfg.Config by value`, t)
+		}
+		if _, squash, _ := fieldKey(field); !squash {
+			return fmt.Errorf(`config type %s must embed catcfg.Config with mapstructure:",squash" tag`, t)
+		}
+	}
+	return nil
+}
+
+func (l *Loader) allSources() ([]Source, error) {
+	sources := append([]Source{}, l.sources...)
+	if l.flags != nil {
+		files, err := configFiles(l.flags)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, files...)
+	}
+	if l.envPrefix != "" {
+		sources = append(sources, envSource{
+			prefix:  l.envPrefix,
+			environ: l.environ,
+		})
+	}
+	if l.flags != nil {
+		sources = append(sources, Flags(l.flags))
+	}
+	return sources, nil
+}
+
+func ParseFile(path string, opts ...loaderOption) (*Config, error) {
+	return NewLoader(append([]loaderOption{WithSources(File(path))}, opts...)...).Load()
+}
+
+// ParseSources loads sources in order and merges them into config,
+// origin of each value could be seen with DumpSources.
+func ParseSources(sources ...Source) (*Config, error) {
+	return NewLoader(WithSources(sources...)).Load()
+}
+
+func (cfg *Config) base() *Config {
+	return cfg
 }
 
 func (cfg *Config) SetLogger(logger catlog.Logger) {
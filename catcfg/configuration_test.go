@@ -1,9 +1,16 @@
 package catcfg
 
 import (
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/surkovvs/gocat/catlog"
 )
 
 func TestParseFile(t *testing.T) {
@@ -11,11 +18,11 @@ func TestParseFile(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Logf("%+v", *cfg)
-	t.Logf("%+v", (*cfg).Kafka)
+	t.Logf("%+v", cfg.Redacted())
+	t.Logf("%+v", cfg.Redacted().Kafka)
 	// t.Logf("%+v", (*cfg).Kafka["produser"])
 	t.Logf("%+v", *cfg.GetLogLvl())
-	prodCfg := (*cfg).Kafka["produser"]
+	prodCfg := (*cfg).Kafka["produser"].ConfigMap()
 	if _, err := kafka.NewConsumer(&prodCfg); err != nil {
 		t.Fatal(err)
 	}
@@ -33,3 +40,328 @@ func TestParseFile(t *testing.T) {
 	// t.Logf("%+v", *cfg)
 	// t.Logf("%+v", cfg.GetLogLvl())
 }
+
+func TestFindMapKeyAmbiguous(t *testing.T) {
+	tree := map[string]any{"linger_ms": 1, "linger.ms": 2, "acks": 1}
+	for i := 0; i < 20; i++ {
+		if key := findMapKey("LINGER_MS", tree); key != "linger.ms" {
+			t.Fatalf("expected linger.ms, got %s", key)
+		}
+	}
+	if key := findMapKey("LINGER_MS", map[string]any{}, tree); key != "linger.ms" {
+		t.Fatalf("expected linger.ms from the second tree, got %s", key)
+	}
+}
+
+func TestParseFileEnv(t *testing.T) {
+	t.Setenv("APP_KAFKA__PRODUSER__BOOTSTRAP_SERVERS", "10.0.0.1:9092")
+	t.Setenv("APP_KAFKA__PRODUSER__LINGER_MS", "5")
+	t.Setenv("APP_KAFKA__PRODUSER__FLUSH_TIMEOUT_MS", "250")
+	t.Setenv("APP_KAFKA__PRODUSER__RETRY__BACKOFF", "50ms")
+	t.Setenv("APP_KAFKA__PRODUSER__LIBRDKAFKA__ACKS", "all")
+	t.Setenv("APP_KAFKA__PRODUSER__LIBRDKAFKA__SASL_PASSWORD", "0123")
+	t.Setenv("APP_DATABASE__PASS", "secret")
+	t.Setenv("APP_DATABASE__POOL__MAX_CONNS", "12")
+	t.Setenv("APP_LOGGER__LEVEL", "error")
+	t.Setenv("APP_UNKNOWN__KEY", "ignored")
+
+	cfg, err := ParseFile(`test_data/config_1.yml`, WithEnvPrefix("APP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["bootstrap.servers"]; got != "10.0.0.1:9092" {
+		t.Fatalf("bootstrap.servers: got %v", got)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["linger.ms"]; got != 5 {
+		t.Fatalf("linger.ms: got %v", got)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["group.id"]; got != "myGroup2" {
+		t.Fatalf("group.id: got %v", got)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["sasl.password"]; got != "0123" {
+		t.Fatalf("sasl.password: got %v", got)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["acks"]; got != "all" {
+		t.Fatalf("acks: got %v", got)
+	}
+	if prod := cfg.Kafka["produser"]; prod.FlushTimeoutMs != 250 || prod.Retry.Backoff != 50*time.Millisecond {
+		t.Fatalf("typed kafka config: got %+v", prod)
+	}
+	if _, ok := cfg.Kafka["produser"].ConfigMap()["flush.timeout.ms"]; ok {
+		t.Fatal("typed key is passed to librdkafka")
+	}
+	if cfg.Pass != "secret" {
+		t.Fatalf("pass: got %v", cfg.Pass)
+	}
+	if cfg.MaxConns == nil || *cfg.MaxConns != 12 {
+		t.Fatalf("max conns: got %v", cfg.MaxConns)
+	}
+	if lvl := cfg.GetLogLvl(); lvl == nil || *lvl != catlog.LevelError {
+		t.Fatalf("log level: got %v", lvl)
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	t.Setenv("APP_DATABASE__USER", "env_user")
+
+	cfg, err := ParseSources(
+		Defaults(map[string]any{
+			"Database": map[string]any{"Port": 6432, "Name": "default_db"},
+		}),
+		File(`test_data/config_1.yml`),
+		OptionalFile(`test_data/not_exists.yml`),
+		File(`test_data/config_override.yml`),
+		Env("APP"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "db.prod" || cfg.Port != 5432 || cfg.User != "env_user" {
+		t.Fatalf("unexpected database config: %+v", cfg.ConfigDB)
+	}
+	if cfg.MaxConns == nil || *cfg.MaxConns != 20 || cfg.MaxConnLifetime == nil {
+		t.Fatalf("unexpected pool config: %+v", cfg.ConfigPool)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["bootstrap.servers"]; got != "kafka.prod:9092" {
+		t.Fatalf("bootstrap.servers: got %v", got)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["group.id"]; got != "myGroup2" {
+		t.Fatalf("group.id: got %v", got)
+	}
+
+	dump := &strings.Builder{}
+	if err := cfg.DumpSources(dump); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"database.host = db.prod (file:test_data/config_override.yml)",
+		"database.user = env_user (env:APP_*)",
+		"database.port = 5432 (file:test_data/config_1.yml)",
+		"kafka.produser[bootstrap.servers] = kafka.prod:9092 (file:test_data/config_override.yml)",
+	} {
+		if !strings.Contains(dump.String(), want) {
+			t.Fatalf("expected %q in dump:\n%s", want, dump)
+		}
+	}
+}
+
+func TestLoaderIsolation(t *testing.T) {
+	var (
+		cfg1, cfg2 *Config
+		err1, err2 error
+		wg         sync.WaitGroup
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		cfg1, err1 = NewLoader(WithSources(File(`test_data/config_1.yml`))).Load()
+	}()
+	go func() {
+		defer wg.Done()
+		cfg2, err2 = ParseFile(`test_data/config_2.yml`)
+	}()
+	wg.Wait()
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+
+	if len(cfg1.Kafka) != 2 || len(cfg2.Kafka) != 0 {
+		t.Fatalf("configs are not isolated: %v, %v", cfg1.Kafka, cfg2.Kafka)
+	}
+	if cfg2.DSN != "" || cfg2.Port != 5432 {
+		t.Fatalf("unexpected database config: %+v", cfg2.ConfigDB)
+	}
+	if viper.ConfigFileUsed() != "" {
+		t.Fatal("global viper instance has been used")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, err := ParseFile(`test_data/config_invalid.yml`)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	var paths []string
+	for _, fe := range verr.Errors {
+		paths = append(paths, fe.Path)
+	}
+	want := []string{
+		"Logger.Level",
+		"Database.Host",
+		"Database.Name",
+		"Database.User",
+		"Database.Pool.MaxConnLifetime",
+		"Database.Pool.MinConns",
+		"Kafka.produser.role",
+		"Kafka.produser.flush.timeout.ms",
+		"Kafka.produser[bootstrap.servers]",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("got paths %v, want %v", paths, want)
+	}
+
+	if _, err := ParseFile(`test_data/config_invalid.yml`, WithoutValidation()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type serviceConfig struct {
+	Config  `mapstructure:",squash"`
+	Service struct {
+		Name    string
+		Workers int
+	} `mapstructure:"Service"`
+}
+
+func (sc *serviceConfig) ValidateConfig(v *Validation) {
+	if sc.Service.Workers < 1 {
+		v.Addf("Service.Workers", "must be positive, got %d", sc.Service.Workers)
+	}
+}
+
+func TestLoadUserType(t *testing.T) {
+	t.Setenv("SVC_SERVICE__WORKERS", "4")
+
+	cfg, err := Load[serviceConfig](
+		WithSources(
+			Defaults(map[string]any{"Service": map[string]any{"Name": "svc"}}),
+			File(`test_data/config_1.yml`),
+		),
+		WithEnvPrefix("SVC"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Service.Name != "svc" || cfg.Service.Workers != 4 {
+		t.Fatalf("unexpected service config: %+v", cfg.Service)
+	}
+	if cfg.Host != "127.0.0.1" || len(cfg.Kafka) != 2 {
+		t.Fatalf("gocat sections were not loaded: %+v", cfg.Config)
+	}
+
+	t.Setenv("SVC_SERVICE__WORKERS", "0")
+	_, err = Load[serviceConfig](WithSources(File(`test_data/config_1.yml`)), WithEnvPrefix("SVC"))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Errors[0].Path != "Service.Workers" {
+		t.Fatalf("expected hook validation error, got %v", err)
+	}
+
+	type notSquashed struct {
+		Config
+	}
+	if _, err := Load[notSquashed](); err == nil {
+		t.Fatal("error expected for not squashed config")
+	}
+	if _, err := Load[struct{}](); err == nil {
+		t.Fatal("error expected for type without config")
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	t.Setenv("TEST_SASL_PASS", "sasl_secret")
+
+	cfg, err := ParseFile(`test_data/config_secrets.yml`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Pass != "db_secret" {
+		t.Fatalf("pass: got %q", cfg.Pass)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["sasl.password"]; got != "sasl_secret" {
+		t.Fatalf("sasl.password: got %v", got)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["client.id"]; got != "literal ${env:NOT_RESOLVED}" {
+		t.Fatalf("client.id: got %v", got)
+	}
+
+	dump := &strings.Builder{}
+	if err := cfg.DumpSources(dump); err != nil {
+		t.Fatal(err)
+	}
+	printed := cfg.Redacted().String() + dump.String()
+	for _, secret := range []string{"db_secret", "sasl_secret"} {
+		if strings.Contains(printed, secret) {
+			t.Fatalf("secret %s is printed:\n%s", secret, printed)
+		}
+	}
+	if cfg.Pass != "db_secret" || cfg.Kafka["produser"].ConfigMap()["sasl.password"] != "sasl_secret" {
+		t.Fatal("config was modified by printing")
+	}
+
+	t.Setenv("APP_DATABASE__PASS", "${vault:db/pass}")
+	if _, err := ParseFile(`test_data/config_secrets.yml`, WithEnvPrefix("APP")); err == nil {
+		t.Fatal("error expected for unknown scheme")
+	}
+	cfg, err = ParseFile(`test_data/config_secrets.yml`, WithEnvPrefix("APP"),
+		WithSecretResolvers(staticResolver{"db/pass": "vault_secret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Pass != "vault_secret" {
+		t.Fatalf("pass: got %q", cfg.Pass)
+	}
+}
+
+type staticResolver map[string]string
+
+func (staticResolver) Scheme() string {
+	return "vault"
+}
+
+func (sr staticResolver) Resolve(ref string) (string, error) {
+	val, ok := sr[ref]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return val, nil
+}
+
+func TestFlags(t *testing.T) {
+	t.Setenv("APP_DATABASE__HOST", "env.host")
+	t.Setenv("APP_DATABASE__USER", "env_user")
+
+	fs := pflag.NewFlagSet("svc", pflag.ContinueOnError)
+	BindFlags(fs, "produser")
+	if err := fs.Parse([]string{
+		"--config=test_data/config_1.yml",
+		"--config", "test_data/config_override.yml",
+		"--log-level=debug",
+		"--database.host=flag.host",
+		"--database.pool.max-conns=7",
+		"--database.log-queries",
+		"--kafka.produser.flush.timeout.ms=300",
+		"--kafka.produser.retry.max.backoff=2s",
+		"--kafka.produser.librdkafka=linger.ms=9",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewLoader(
+		WithSources(Defaults(map[string]any{"Database": map[string]any{"Name": "default_db"}})),
+		WithEnvPrefix("APP"),
+		WithFlags(fs),
+	).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "flag.host" || cfg.User != "env_user" || cfg.Name != "postgres" {
+		t.Fatalf("unexpected database config: %+v", cfg.ConfigDB)
+	}
+	if cfg.MaxConns == nil || *cfg.MaxConns != 7 || !cfg.LogQueries {
+		t.Fatalf("unexpected pool config: %+v", cfg.ConfigPool)
+	}
+	if lvl := cfg.GetLogLvl(); lvl == nil || *lvl != catlog.LevelDebug {
+		t.Fatalf("log level: got %v", lvl)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["bootstrap.servers"]; got != "kafka.prod:9092" {
+		t.Fatalf("bootstrap.servers: got %v", got)
+	}
+	if kcfg := cfg.Kafka["produser"]; kcfg.FlushTimeoutMs != 300 || kcfg.Retry.MaxBackoff != 2*time.Second {
+		t.Fatalf("unexpected kafka config: %+v", kcfg)
+	}
+	if got := cfg.Kafka["produser"].ConfigMap()["linger.ms"]; got != "9" {
+		t.Fatalf("linger.ms: got %v", got)
+	}
+}
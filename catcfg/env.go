@@ -0,0 +1,204 @@
+package catcfg
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Environment variables mapping, applied over values from file:
+//
+//	<PREFIX>_<SECTION>__<FIELD>[__<FIELD>...]=value
+//
+// Levels of the path are separated with double underscore. Struct fields are
+// matched case insensitive with single underscores ignored, so both
+// APP_DATABASE__POOL__MAXCONNS and APP_DATABASE__POOL__MAX_CONNS are bound to
+// Database.Pool.MaxConns, dots and dashes in field keys are ignored too
+// (APP_KAFKA__PRODUSER__FLUSH_TIMEOUT_MS). Map keys (e.g. Kafka tags) and
+// unknown keys of structs with remain map (librdkafka properties set directly
+// in the Kafka tag) are matched with existing keys with '.' and '-' replaced
+// by '_', if there is no such key, new one is created in lower case with '_'
+// replaced by '.':
+//
+//	APP_KAFKA__PRODUSER__BOOTSTRAP_SERVERS -> Kafka["produser"].Passthrough["bootstrap.servers"]
+//
+// Values for untyped map entries are parsed as bool or int if possible.
+
+const envLevelSep = "__"
+
+// envLayer returns settings layer built from environment, base is used
+// to match existing map keys.
+func envLayer(base map[string]any, t reflect.Type, prefix string, environ []string) map[string]any {
+	layer := make(map[string]any)
+	prefix = strings.ToUpper(prefix) + "_"
+	for _, kv := range environ {
+		key, val, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(strings.ToUpper(key), prefix) {
+			continue
+		}
+		segs := strings.Split(key[len(prefix):], envLevelSep)
+		setEnvValue(layer, base, t, segs, val)
+	}
+	return layer
+}
+
+// setEnvValue returns false if path does not match target type
+func setEnvValue(layer, base map[string]any, t reflect.Type, segs []string, val string) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if len(segs) == 0 || segs[0] == "" {
+		return false
+	}
+
+	var (
+		key  string
+		elem reflect.Type
+	)
+	switch t.Kind() {
+	case reflect.Struct:
+		var ok bool
+		key, elem, ok = findField(t, segs[0])
+		if !ok {
+			// unknown keys go to the remain map, if struct has it
+			if elem, ok = remainElem(t); !ok {
+				return false
+			}
+			key = findMapKey(segs[0], layer, base)
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return false
+		}
+		key = findMapKey(segs[0], layer, base)
+		elem = t.Elem()
+	case reflect.Interface:
+		key = findMapKey(segs[0], layer, base)
+		elem = t
+	default:
+		return false
+	}
+
+	if len(segs) == 1 {
+		if elem.Kind() == reflect.Interface {
+			layer[key] = parseEnvValue(val)
+		} else {
+			layer[key] = val
+		}
+		return true
+	}
+
+	sub, ok := layer[key].(map[string]any)
+	if !ok {
+		sub = make(map[string]any)
+	}
+	baseSub, _ := base[key].(map[string]any)
+	if !setEnvValue(sub, baseSub, elem, segs[1:], val) {
+		return false
+	}
+	layer[key] = sub
+	return true
+}
+
+// findField returns settings key and type of struct field matched with env segment
+func findField(t reflect.Type, seg string) (string, reflect.Type, bool) {
+	want := normalizeFieldName(seg)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, squash, remain := fieldKey(field)
+		if name == "-" || remain {
+			continue
+		}
+		if squash {
+			if key, elem, ok := findField(field.Type, seg); ok {
+				return key, elem, true
+			}
+			continue
+		}
+		if normalizeFieldName(name) == want {
+			return strings.ToLower(name), field.Type, true
+		}
+	}
+	return "", nil, false
+}
+
+// remainElem returns element type of the map collecting unknown keys of the struct
+func remainElem(t reflect.Type) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, _, remain := fieldKey(field); remain && field.Type.Kind() == reflect.Map {
+			return field.Type.Elem(), true
+		}
+		if _, squash, _ := fieldKey(field); squash && field.Type.Kind() == reflect.Struct {
+			if elem, ok := remainElem(field.Type); ok {
+				return elem, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// fieldKey returns key of the field in settings the same way as mapstructure does,
+// remain field has no key, its entries are stored on the level of the struct.
+func fieldKey(field reflect.StructField) (name string, squash, remain bool) {
+	tag := field.Tag.Get("mapstructure")
+	name, opts, _ := strings.Cut(tag, ",")
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "squash":
+			squash = field.Type.Kind() == reflect.Struct
+		case "remain":
+			remain = true
+		}
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, squash, remain
+}
+
+// findMapKey returns existing key of trees matched with env segment, trees are
+// checked in order, if several keys of tree match, e.g. linger.ms and linger_ms,
+// the first one in sorted order is returned, so mapping does not depend on map order
+func findMapKey(seg string, trees ...map[string]any) string {
+	want := strings.ToUpper(seg)
+	for _, tree := range trees {
+		var matched []string
+		for key := range tree {
+			if envKey(key) == want {
+				matched = append(matched, key)
+			}
+		}
+		if len(matched) != 0 {
+			slices.Sort(matched)
+			return matched[0]
+		}
+	}
+	return strings.ReplaceAll(strings.ToLower(seg), "_", ".")
+}
+
+func envKey(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+func normalizeFieldName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("_", "", ".", "", "-", "").Replace(name))
+}
+
+func parseEnvValue(val string) any {
+	switch {
+	case strings.EqualFold(val, "true"):
+		return true
+	case strings.EqualFold(val, "false"):
+		return false
+	}
+	// only canonical ints, so e.g. "0123" or "+1" are kept strings
+	if i, err := strconv.Atoi(val); err == nil && strconv.Itoa(i) == val {
+		return i
+	}
+	return val
+}
@@ -0,0 +1,169 @@
+package catcfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+
+	sourceDefault = "default"
+)
+
+type explainNode struct {
+	key      string
+	children []*explainNode
+	value    any
+	source   string
+	leaf     bool
+}
+
+// Explain renders effective config with secrets redacted and the source of
+// each value, cfg is *Config or pointer to user type embedding Config.
+// Values not provided by any source are marked as default.
+//
+// YAML sources are rendered as line comments, JSON leaves are {"value": ..., "source": ...}.
+func Explain(w io.Writer, cfg any, format Format) error {
+	embedded, ok := cfg.(interface{ base() *Config })
+	if !ok {
+		return fmt.Errorf(`config type %T does not embed catcfg.Config`, cfg)
+	}
+	meta := embedded.base().meta
+
+	root := &explainNode{}
+	explainValue(root, reflect.ValueOf(cfg), nil, meta, newMasker(meta))
+
+	switch format {
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(root.yamlNode()); err != nil {
+			return fmt.Errorf(`encode yaml: %w`, err)
+		}
+		return enc.Close()
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(root.jsonValue()); err != nil {
+			return fmt.Errorf(`encode json: %w`, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf(`unknown format %q`, format)
+	}
+}
+
+func explainValue(node *explainNode, v reflect.Value, path []string, meta *loadMeta, mask masker) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			node.leaf = true
+			node.source = sourceOf(path, meta)
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		node.leaf = true
+		node.value = v.Interface().(time.Duration).String()
+	case v.Kind() == reflect.Struct:
+		explainStruct(node, v, path, meta, mask)
+		return
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			child := &explainNode{key: key}
+			keyPath := append(path[:len(path):len(path)], strings.ToLower(key))
+			explainValue(child, v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())), keyPath, meta, mask)
+			node.children = append(node.children, child)
+		}
+		return
+	default:
+		node.leaf = true
+		node.value, _ = mask.value(path, v.Interface())
+	}
+	if meta != nil {
+		if _, ok := meta.secrets[joinPath(path)]; ok {
+			node.value = redactedMask
+		}
+	}
+	node.source = sourceOf(path, meta)
+}
+
+func explainStruct(node *explainNode, v reflect.Value, path []string, meta *loadMeta, mask masker) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, squash, remain := fieldKey(field)
+		if name == "-" {
+			continue
+		}
+		if squash || remain {
+			explainValue(node, v.Field(i), path, meta, mask)
+			continue
+		}
+		child := &explainNode{key: name}
+		explainValue(child, v.Field(i), append(path[:len(path):len(path)], strings.ToLower(name)), meta, mask)
+		node.children = append(node.children, child)
+	}
+}
+
+func sourceOf(path []string, meta *loadMeta) string {
+	if meta == nil {
+		return sourceDefault
+	}
+	if source, ok := meta.origins[joinPath(path)]; ok {
+		return source
+	}
+	return sourceDefault
+}
+
+func (node *explainNode) yamlNode() *yaml.Node {
+	if node.leaf {
+		valNode := &yaml.Node{}
+		if err := valNode.Encode(node.value); err != nil {
+			valNode = &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(node.value)}
+		}
+		valNode.LineComment = node.source
+		return valNode
+	}
+	mapNode := &yaml.Node{Kind: yaml.MappingNode}
+	for _, child := range node.children {
+		mapNode.Content = append(mapNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: child.key},
+			child.yamlNode())
+	}
+	return mapNode
+}
+
+func (node *explainNode) jsonValue() any {
+	if node.leaf {
+		return map[string]any{
+			"value":  node.value,
+			"source": node.source,
+		}
+	}
+	res := make(map[string]any, len(node.children))
+	for _, child := range node.children {
+		res[child.key] = child.jsonValue()
+	}
+	return res
+}
@@ -0,0 +1,201 @@
+package catcfg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/pflag"
+	"github.com/surkovvs/gocat/catkafka"
+)
+
+// Command line flags are bound to config paths with flag annotations, values
+// of changed flags are applied over all other sources:
+//
+//	flags > env > files (--config and WithSources) > defaults
+//
+// Flags of built-in sections are generated from field names in kebab case,
+// e.g. --database.host, --database.pool.max-conns, --logger.console-encoder.
+// Kafka flags are generated for tags passed to BindFlags, e.g. --kafka.produser.topic,
+// --kafka.produser.retry.max.backoff, librdkafka properties are set with repeated
+// --kafka.produser.librdkafka=linger.ms=5.
+const (
+	FlagConfig   = "config"
+	FlagLogLevel = "log-level"
+
+	flagPathAnnotation    = "catcfg.path"
+	flagUntypedAnnotation = "catcfg.untyped"
+	flagMapAnnotation     = "catcfg.map"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// BindFlags registers --config, --log-level and flags of the Logger and
+// Database sections and of Kafka tags in fs, fs must be parsed before loading
+// with WithFlags.
+func BindFlags(fs *pflag.FlagSet, kafkaTags ...string) {
+	fs.StringSlice(FlagConfig, nil, "config file, could be repeated, later files win")
+	fs.String(FlagLogLevel, "", "shorthand for --logger.level")
+	_ = BindFlagPath(fs, FlagLogLevel, "Logger.Level")
+	_ = fs.SetAnnotation(FlagLogLevel, flagUntypedAnnotation, nil)
+
+	cfgType := reflect.TypeOf(Config{})
+	for i := 0; i < cfgType.NumField(); i++ {
+		field := cfgType.Field(i)
+		name, _, _ := fieldKey(field)
+		if !field.IsExported() || name == "-" || field.Type.Kind() != reflect.Struct {
+			continue
+		}
+		bindStructFlags(fs, field.Type, []string{flagSegment(field, name)}, []string{name})
+	}
+
+	kafkaType := reflect.TypeOf(catkafka.ConfigKafka{})
+	for _, tag := range kafkaTags {
+		flagPath := []string{"kafka", tag}
+		path := []string{"Kafka", tag}
+		bindStructFlags(fs, kafkaType, flagPath, path)
+
+		flag := strings.Join(append(flagPath, "librdkafka"), ".")
+		fs.StringToString(flag, nil, "librdkafka properties of "+tag+", key=value, could be repeated")
+		_ = fs.SetAnnotation(flag, flagPathAnnotation, append(path, "librdkafka"))
+		_ = fs.SetAnnotation(flag, flagMapAnnotation, nil)
+	}
+}
+
+// BindFlagPath binds user defined flag to config path, e.g. "Service.Port"
+// for the field of user config type loaded with Load.
+func BindFlagPath(fs *pflag.FlagSet, flag, path string) error {
+	return fs.SetAnnotation(flag, flagPathAnnotation, strings.Split(path, "."))
+}
+
+func bindStructFlags(fs *pflag.FlagSet, t reflect.Type, flagPath, path []string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, squash, remain := fieldKey(field)
+		if !field.IsExported() || name == "-" || remain {
+			continue
+		}
+		if squash {
+			bindStructFlags(fs, field.Type, flagPath, path)
+			continue
+		}
+		fieldFlagPath := append(flagPath[:len(flagPath):len(flagPath)], flagSegment(field, name))
+		fieldPath := append(path[:len(path):len(path)], name)
+
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft == durationType:
+		case ft.Kind() == reflect.Struct:
+			bindStructFlags(fs, ft, fieldFlagPath, fieldPath)
+			continue
+		case ft.Kind() == reflect.Map, ft.Kind() == reflect.Slice, ft.Kind() == reflect.Func, ft.Kind() == reflect.Chan:
+			continue
+		}
+
+		flag := strings.Join(fieldFlagPath, ".")
+		if fs.Lookup(flag) != nil {
+			continue
+		}
+		fs.String(flag, "", strings.Join(fieldPath, "."))
+		if ft.Kind() == reflect.Bool {
+			fs.Lookup(flag).NoOptDefVal = "true"
+		}
+		// path is set as is, as key names could contain dots, e.g. partition.hash
+		_ = fs.SetAnnotation(flag, flagPathAnnotation, fieldPath)
+		if ft.Kind() == reflect.Interface {
+			// parsed the same way as env values, e.g. numeric log level
+			_ = fs.SetAnnotation(flag, flagUntypedAnnotation, nil)
+		}
+	}
+}
+
+// flagSegment returns field name in kebab case, explicit tag name is used as is
+func flagSegment(field reflect.StructField, name string) string {
+	if name != field.Name {
+		return strings.ToLower(name)
+	}
+	runes := []rune(name)
+	sb := strings.Builder{}
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			sb.WriteByte('-')
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+type flagsSource struct {
+	fs *pflag.FlagSet
+}
+
+// Flags source provides values of changed flags bound to config paths
+// with BindFlags or BindFlagPath.
+func Flags(fs *pflag.FlagSet) Source {
+	return flagsSource{fs: fs}
+}
+
+func (fsrc flagsSource) Name() string {
+	return "flags"
+}
+
+func (fsrc flagsSource) Load(map[string]any, reflect.Type) (map[string]any, error) {
+	layer := make(map[string]any)
+	var err error
+	fsrc.fs.Visit(func(flag *pflag.Flag) {
+		path := flag.Annotations[flagPathAnnotation]
+		if len(path) == 0 || err != nil {
+			return
+		}
+		node := layer
+		for _, key := range path[:len(path)-1] {
+			key = strings.ToLower(key)
+			sub, ok := node[key].(map[string]any)
+			if !ok {
+				if _, exists := node[key]; exists {
+					err = fmt.Errorf(`flag --%s: path %s conflicts with other flag`, flag.Name, strings.Join(path, "."))
+					return
+				}
+				sub = make(map[string]any)
+				node[key] = sub
+			}
+			node = sub
+		}
+		var val any = flag.Value.String()
+		if _, untyped := flag.Annotations[flagUntypedAnnotation]; untyped {
+			val = parseEnvValue(flag.Value.String())
+		}
+		if _, isMap := flag.Annotations[flagMapAnnotation]; isMap {
+			props, _ := fsrc.fs.GetStringToString(flag.Name)
+			propsMap := make(map[string]any, len(props))
+			for k, v := range props {
+				propsMap[k] = v
+			}
+			val = propsMap
+		}
+		node[strings.ToLower(path[len(path)-1])] = val
+	})
+	return layer, err
+}
+
+// configFiles returns sources of files set with --config flag
+func configFiles(fs *pflag.FlagSet) ([]Source, error) {
+	if fs.Lookup(FlagConfig) == nil {
+		return nil, nil
+	}
+	paths, err := fs.GetStringSlice(FlagConfig)
+	if err != nil {
+		return nil, fmt.Errorf(`flag --%s: %w`, FlagConfig, err)
+	}
+	sources := make([]Source, 0, len(paths))
+	for _, path := range paths {
+		sources = append(sources, File(path))
+	}
+	return sources, nil
+}
@@ -0,0 +1,120 @@
+package catcfg
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type loadMeta struct {
+	settings map[string]any    // merged raw settings
+	origins  map[string]string // path of value -> source name
+	secrets  map[string]struct{}
+}
+
+// mergeSettings deep merges src into dst, if origins passed,
+// source name is recorded for each leaf of src.
+func mergeSettings(dst, src map[string]any, origins map[string]string, path []string, source string) {
+	for key, val := range src {
+		keyPath := append(slices.Clip(path), key)
+		srcMap, srcIsMap := val.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		switch {
+		case srcIsMap && dstIsMap:
+			mergeSettings(dstMap, srcMap, origins, keyPath, source)
+		case srcIsMap:
+			dstMap = make(map[string]any, len(srcMap))
+			dropOrigins(origins, keyPath)
+			mergeSettings(dstMap, srcMap, origins, keyPath, source)
+			dst[key] = dstMap
+		default:
+			dropOrigins(origins, keyPath)
+			dst[key] = val
+			if origins != nil {
+				origins[joinPath(keyPath)] = source
+			}
+		}
+	}
+}
+
+func dropOrigins(origins map[string]string, path []string) {
+	if origins == nil {
+		return
+	}
+	prefix := joinPath(path)
+	for key := range origins {
+		if key == prefix || strings.HasPrefix(key, prefix+".") || strings.HasPrefix(key, prefix+"[") {
+			delete(origins, key)
+		}
+	}
+}
+
+func lowerKeys(settings map[string]any) map[string]any {
+	if settings == nil {
+		return nil
+	}
+	res := make(map[string]any, len(settings))
+	for key, val := range settings {
+		if sub, ok := val.(map[string]any); ok {
+			val = lowerKeys(sub)
+		}
+		res[strings.ToLower(key)] = val
+	}
+	return res
+}
+
+// joinPath joins keys with dots, keys containing dot (e.g. librdkafka
+// properties) are wrapped with brackets: kafka.produser[bootstrap.servers]
+func joinPath(path []string) string {
+	sb := strings.Builder{}
+	for i, key := range path {
+		switch {
+		case strings.Contains(key, "."):
+			sb.WriteString("[" + key + "]")
+		case i > 0:
+			sb.WriteString("." + key)
+		default:
+			sb.WriteString(key)
+		}
+	}
+	return sb.String()
+}
+
+// DumpSources writes each loaded value with the source it came from,
+// available for configs made by Loader.
+func (cfg *Config) DumpSources(w io.Writer) error {
+	if cfg.meta == nil {
+		_, err := fmt.Fprintln(w, "config was not loaded from sources")
+		return err
+	}
+	mask := newMasker(cfg.meta)
+	var lines []string
+	walkSettings(cfg.meta.settings, nil, func(path []string, val any) {
+		key := joinPath(path)
+		if _, ok := cfg.meta.secrets[key]; ok {
+			val = redactedMask
+		} else {
+			val, _ = mask.value(path, val)
+		}
+		lines = append(lines, fmt.Sprintf("%s = %v (%s)", key, val, cfg.meta.origins[key]))
+	})
+	slices.Sort(lines)
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkSettings(settings map[string]any, path []string, fn func(path []string, val any)) {
+	for key, val := range settings {
+		keyPath := append(slices.Clip(path), key)
+		if sub, ok := val.(map[string]any); ok {
+			walkSettings(sub, keyPath, fn)
+			continue
+		}
+		fn(keyPath, val)
+	}
+}
@@ -0,0 +1,186 @@
+package catcfg
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const redactedMask = "******"
+
+// paths of values which are always redacted, * matches any key
+var sensitivePaths = [][]string{
+	{"database", "pass"},
+	{"kafka", "*", "sasl.password"},
+	{"kafka", "*", "ssl.key.password"},
+	{"kafka", "*", "ssl.keystore.password"},
+	{"kafka", "*", "sasl.oauthbearer.client.secret"},
+	{"kafka", "*", "librdkafka", "sasl.password"},
+	{"kafka", "*", "librdkafka", "ssl.key.password"},
+	{"kafka", "*", "librdkafka", "ssl.keystore.password"},
+	{"kafka", "*", "librdkafka", "sasl.oauthbearer.client.secret"},
+}
+
+var dsnPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s]*)`)
+
+// RedactedConfig is config with secrets masked, it is separate type, so its String
+// method is not promoted to user config types embedding Config.
+type RedactedConfig struct {
+	Config
+}
+
+// String prints config with secrets redacted.
+func (rc RedactedConfig) String() string {
+	cfg := rc.Config
+	cfg.meta = nil
+	return fmt.Sprintf("%+v", cfg)
+}
+
+// Redacted returns copy of config with known secret fields, passwords in DSN and
+// resolved secret references masked, maps are copied, so original config is not affected.
+// Print config with it, e.g. fmt.Println(cfg.Redacted()).
+func (cfg Config) Redacted() RedactedConfig {
+	mask := newMasker(cfg.meta)
+	return RedactedConfig{redactValue(reflect.ValueOf(cfg), nil, mask).Interface().(Config)}
+}
+
+type masker func(path []string, val string) (string, bool)
+
+func newMasker(meta *loadMeta) masker {
+	var secrets map[string]struct{}
+	if meta != nil {
+		secrets = meta.secrets
+	}
+	return func(path []string, val string) (string, bool) {
+		if val == "" {
+			return val, false
+		}
+		if _, ok := secrets[joinPath(path)]; ok {
+			return redactedMask, true
+		}
+		if isSensitivePath(path) {
+			return redactedMask, true
+		}
+		if slices.Equal(path, []string{"database", "dsn"}) ||
+			slices.Equal(path, []string{"database", "replicas", "dsn"}) {
+			return redactDSN(val), true
+		}
+		return val, false
+	}
+}
+
+// value masks value of any scalar kind, as values of env and YAML, which look
+// like numbers or bools, are decoded so
+func (mask masker) value(path []string, val any) (any, bool) {
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.String:
+		return mask(path, reflect.ValueOf(val).String())
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return mask(path, fmt.Sprint(val))
+	}
+	return val, false
+}
+
+func isSensitivePath(path []string) bool {
+	for _, sensitive := range sensitivePaths {
+		if len(sensitive) != len(path) {
+			continue
+		}
+		match := true
+		for i := range sensitive {
+			if sensitive[i] != "*" && sensitive[i] != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// redactDSN masks password in URL or keyword/value connection string
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedMask)
+		}
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", redactedMask)
+			u.RawQuery = query.Encode()
+		}
+		return u.String()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redactedMask)
+}
+
+func redactValue(v reflect.Value, path []string, mask masker) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		res := reflect.New(v.Type()).Elem()
+		res.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, squash, remain := fieldKey(field)
+			if name == "-" {
+				continue
+			}
+			fieldPath := path
+			if !squash && !remain {
+				fieldPath = append(path[:len(path):len(path)], strings.ToLower(name))
+			}
+			res.Field(i).Set(redactValue(v.Field(i), fieldPath, mask))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		res := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			keyPath := append(path[:len(path):len(path)], strings.ToLower(iter.Key().String()))
+			res.SetMapIndex(iter.Key(), redactValue(iter.Value(), keyPath, mask))
+		}
+		return res
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type()).Elem()
+		// secret decoded as number or bool is replaced with masked string
+		if elem := v.Elem(); elem.Kind() != reflect.String && reflect.TypeOf(redactedMask).AssignableTo(v.Type()) {
+			if masked, ok := mask.value(path, elem.Interface()); ok {
+				res.Set(reflect.ValueOf(masked))
+				return res
+			}
+		}
+		res.Set(redactValue(v.Elem(), path, mask))
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(redactValue(v.Index(i), path, mask)) // elements share path
+		}
+		return res
+	case reflect.String:
+		if masked, ok := mask(path, v.String()); ok {
+			return reflect.ValueOf(masked).Convert(v.Type())
+		}
+	}
+	return v
+}
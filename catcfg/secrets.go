@@ -0,0 +1,151 @@
+package catcfg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// SecretResolver resolves secret references ${<scheme>:<ref>} in string config values,
+// references are resolved after all sources merged. Resolved values are redacted
+// on config printing.
+type SecretResolver interface {
+	Scheme() string
+	Resolve(ref string) (string, error)
+}
+
+// secretRef matches escaped $${...} as well, which is replaced by literal ${...}
+var secretRef = regexp.MustCompile(`\$?\$\{([A-Za-z][A-Za-z0-9+.-]*):([^}]*)\}`)
+
+type fileResolver struct{}
+
+// FileResolver reads secret from file, trailing line break is trimmed:
+// ${file:/var/run/secrets/db-pass}
+func FileResolver() SecretResolver {
+	return fileResolver{}
+}
+
+func (fileResolver) Scheme() string {
+	return "file"
+}
+
+func (fileResolver) Resolve(ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+type envResolver struct{}
+
+// EnvResolver reads secret from environment variable, variable must be set: ${env:DB_PASS}
+func EnvResolver() SecretResolver {
+	return envResolver{}
+}
+
+func (envResolver) Scheme() string {
+	return "env"
+}
+
+func (envResolver) Resolve(ref string) (string, error) {
+	val, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return val, nil
+}
+
+func defaultResolvers() map[string]SecretResolver {
+	return map[string]SecretResolver{
+		"file": FileResolver(),
+		"env":  EnvResolver(),
+	}
+}
+
+// resolveSecrets replaces references in settings, paths of values
+// containing secrets are returned.
+func resolveSecrets(settings map[string]any, resolvers map[string]SecretResolver) (map[string]struct{}, error) {
+	secrets := make(map[string]struct{})
+	v := &Validation{}
+	resolveSecretsIn(settings, nil, resolvers, secrets, v)
+	if err := v.Err(); err != nil {
+		return nil, fmt.Errorf(`resolve secrets: %w`, err)
+	}
+	return secrets, nil
+}
+
+func resolveSecretsIn(
+	settings map[string]any,
+	path []string,
+	resolvers map[string]SecretResolver,
+	secrets map[string]struct{},
+	v *Validation,
+) {
+	for key, val := range settings {
+		keyPath := append(path[:len(path):len(path)], key)
+		switch typed := val.(type) {
+		case map[string]any:
+			resolveSecretsIn(typed, keyPath, resolvers, secrets, v)
+		case string:
+			resolved, isSecret, err := resolveString(typed, resolvers)
+			if err != nil {
+				v.Addf(joinPath(keyPath), "%s", err)
+				continue
+			}
+			if isSecret {
+				settings[key] = resolved
+				secrets[joinPath(keyPath)] = struct{}{}
+			} else if resolved != typed {
+				settings[key] = resolved
+			}
+		case []any:
+			for i, elem := range typed {
+				str, ok := elem.(string)
+				if !ok {
+					continue
+				}
+				resolved, isSecret, err := resolveString(str, resolvers)
+				if err != nil {
+					v.Addf(fmt.Sprintf("%s[%d]", joinPath(keyPath), i), "%s", err)
+					continue
+				}
+				typed[i] = resolved
+				if isSecret {
+					secrets[joinPath(keyPath)] = struct{}{}
+				}
+			}
+		}
+	}
+}
+
+func resolveString(val string, resolvers map[string]SecretResolver) (string, bool, error) {
+	if !strings.Contains(val, "${") {
+		return val, false, nil
+	}
+	var (
+		errs     []error
+		isSecret bool
+	)
+	resolved := secretRef.ReplaceAllStringFunc(val, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		match := secretRef.FindStringSubmatch(ref)
+		resolver, ok := resolvers[match[1]]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown secret scheme %q", match[1]))
+			return ref
+		}
+		secret, err := resolver.Resolve(match[2])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolve %s secret: %w", match[1], err))
+			return ref
+		}
+		isSecret = true
+		return secret
+	})
+	return resolved, isSecret, errors.Join(errs...)
+}
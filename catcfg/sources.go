@@ -0,0 +1,233 @@
+package catcfg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Source provides settings layer, layers are deep merged in order of sources,
+// values of the later source win. Keys of returned settings are case insensitive.
+type Source interface {
+	Name() string
+	// base contains settings merged from previous sources,
+	// target is type settings will be decoded to.
+	Load(base map[string]any, target reflect.Type) (map[string]any, error)
+}
+
+// expandable source is replaced by its sub sources, so origins
+// of values are tracked with better precision.
+type expandable interface {
+	Expand() ([]Source, error)
+}
+
+// watchable source provides paths to watch for changes,
+// empty file name means any config file of the directory.
+type watchable interface {
+	watchTargets() []watchTarget
+}
+
+type watchTarget struct {
+	dir  string
+	file string
+}
+
+var configExts = []string{".yml", ".yaml", ".json", ".toml"}
+
+type fileSource struct {
+	path     string
+	optional bool
+}
+
+// File source reads config file, format is defined by extension.
+func File(path string) Source {
+	return fileSource{path: path}
+}
+
+// OptionalFile source is skipped if file does not exist,
+// useful for developer local overrides.
+func OptionalFile(path string) Source {
+	return fileSource{path: path, optional: true}
+}
+
+func (fsrc fileSource) Name() string {
+	return "file:" + fsrc.path
+}
+
+func (fsrc fileSource) Load(map[string]any, reflect.Type) (map[string]any, error) {
+	if fsrc.optional {
+		if _, err := os.Stat(fsrc.path); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	v := viper.NewWithOptions(viper.KeyDelimiter("|"))
+	v.SetConfigFile(fsrc.path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf(`read config: %w`, err)
+	}
+	return v.AllSettings(), nil
+}
+
+func (fsrc fileSource) watchTargets() []watchTarget {
+	return []watchTarget{{
+		dir:  filepath.Dir(filepath.Clean(fsrc.path)),
+		file: filepath.Base(fsrc.path),
+	}}
+}
+
+type dirSource struct {
+	path string
+}
+
+// Dir source reads all config files of directory in lexical order,
+// subdirectories are ignored.
+func Dir(path string) Source {
+	return dirSource{path: path}
+}
+
+func (dsrc dirSource) Name() string {
+	return "dir:" + dsrc.path
+}
+
+func (dsrc dirSource) Load(base map[string]any, target reflect.Type) (map[string]any, error) {
+	sources, err := dsrc.Expand()
+	if err != nil {
+		return nil, err
+	}
+	layer := make(map[string]any)
+	for _, src := range sources {
+		sub, err := src.Load(base, target)
+		if err != nil {
+			return nil, err
+		}
+		mergeSettings(layer, sub, nil, nil, "")
+	}
+	return layer, nil
+}
+
+func (dsrc dirSource) Expand() ([]Source, error) {
+	entries, err := os.ReadDir(dsrc.path)
+	if err != nil {
+		return nil, fmt.Errorf(`read config dir: %w`, err)
+	}
+	var sources []Source
+	for _, entry := range entries { // sorted by name
+		if entry.IsDir() || !slices.Contains(configExts, strings.ToLower(filepath.Ext(entry.Name()))) {
+			continue
+		}
+		sources = append(sources, File(filepath.Join(dsrc.path, entry.Name())))
+	}
+	return sources, nil
+}
+
+func (dsrc dirSource) watchTargets() []watchTarget {
+	return []watchTarget{{
+		dir: filepath.Clean(dsrc.path),
+	}}
+}
+
+type fsSource struct {
+	fsys fs.FS
+	path string
+}
+
+// FS source reads config file from file system, e.g. embedded defaults.
+func FS(fsys fs.FS, path string) Source {
+	return fsSource{fsys: fsys, path: path}
+}
+
+func (fsrc fsSource) Name() string {
+	return "fs:" + fsrc.path
+}
+
+func (fsrc fsSource) Load(map[string]any, reflect.Type) (map[string]any, error) {
+	data, err := fs.ReadFile(fsrc.fsys, fsrc.path)
+	if err != nil {
+		return nil, fmt.Errorf(`read config: %w`, err)
+	}
+	v := viper.NewWithOptions(viper.KeyDelimiter("|"))
+	v.SetConfigType(strings.TrimPrefix(filepath.Ext(fsrc.path), "."))
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf(`read config: %w`, err)
+	}
+	return v.AllSettings(), nil
+}
+
+type defaultsSource struct {
+	values map[string]any
+}
+
+// Defaults source provides values as is, keys are config paths, e.g.
+//
+//	map[string]any{"Logger": map[string]any{"Level": "warn"}}
+func Defaults(values map[string]any) Source {
+	return defaultsSource{values: values}
+}
+
+func (dsrc defaultsSource) Name() string {
+	return "defaults"
+}
+
+func (dsrc defaultsSource) Load(map[string]any, reflect.Type) (map[string]any, error) {
+	return lowerKeys(dsrc.values), nil
+}
+
+type envSource struct {
+	prefix  string
+	environ func() []string
+}
+
+// Env source overrides values with environment variables, mapping of
+// variables is described in env.go.
+func Env(prefix string) Source {
+	return envSource{prefix: prefix, environ: os.Environ}
+}
+
+func (esrc envSource) Name() string {
+	return "env:" + strings.ToUpper(esrc.prefix) + "_*"
+}
+
+func (esrc envSource) Load(base map[string]any, target reflect.Type) (map[string]any, error) {
+	return envLayer(base, target, esrc.prefix, esrc.environ()), nil
+}
+
+func loadSources(sources []Source, target reflect.Type) (map[string]any, map[string]string, error) {
+	settings := make(map[string]any)
+	origins := make(map[string]string)
+	for _, src := range sources {
+		subs := []Source{src}
+		if exp, ok := src.(expandable); ok {
+			var err error
+			if subs, err = exp.Expand(); err != nil {
+				return nil, nil, fmt.Errorf(`source %s: %w`, src.Name(), err)
+			}
+		}
+		for _, sub := range subs {
+			layer, err := sub.Load(settings, target)
+			if err != nil {
+				return nil, nil, fmt.Errorf(`source %s: %w`, sub.Name(), err)
+			}
+			mergeSettings(settings, lowerKeys(layer), origins, nil, sub.Name())
+		}
+	}
+	return settings, origins, nil
+}
+
+func decodeSettings(settings map[string]any, target any) error {
+	v := viper.NewWithOptions(viper.KeyDelimiter("|"))
+	if err := v.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf(`merge settings: %w`, err)
+	}
+	if err := v.Unmarshal(target); err != nil {
+		return fmt.Errorf(`unmarshal config: %w`, err)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package catcfg
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/surkovvs/gocat/catdb"
+	"github.com/surkovvs/gocat/catkafka"
+	"github.com/surkovvs/gocat/catlog"
+)
+
+// FieldError describes problem of single config value, path is full key of the value.
+type FieldError struct {
+	Path string
+	Msg  string
+}
+
+func (fe FieldError) Error() string {
+	return fe.Path + ": " + fe.Msg
+}
+
+// ValidationError contains all problems found in config.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (ve *ValidationError) Error() string {
+	sb := strings.Builder{}
+	sb.WriteString("config validation failed:")
+	for _, fe := range ve.Errors {
+		sb.WriteString("\n\t" + fe.Error())
+	}
+	return sb.String()
+}
+
+// Validation collects config problems.
+type Validation struct {
+	errs []FieldError
+}
+
+func (v *Validation) Addf(path, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{
+		Path: path,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *Validation) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+// ConfigValidator is validation hook for user config structs, which embed Config,
+// it is called by Loader after checks of gocat sections.
+type ConfigValidator interface {
+	ValidateConfig(v *Validation)
+}
+
+// Validate checks gocat sections of config, all problems are reported at once.
+func (cfg *Config) Validate() error {
+	v := &Validation{}
+	cfg.validate(v)
+	return v.Err()
+}
+
+func (cfg *Config) validate(v *Validation) {
+	validateLog(v, "Logger", cfg.ConfigLog)
+	validateDB(v, "Database", cfg.ConfigDB)
+	validateKafka(v, "Kafka", cfg.Kafka)
+}
+
+func validateLog(v *Validation, path string, cfg catlog.ConfigLog) {
+	if cfg.Level == nil {
+		return
+	}
+	switch lvl := cfg.Level.(type) {
+	case int:
+		if lvl < int(catlog.LevelDebug) || lvl > int(catlog.LevelError) {
+			v.Addf(path+".Level", "must be in range %d-%d, got %d",
+				catlog.LevelDebug, catlog.LevelError, lvl)
+		}
+	case string:
+		if cfg.GetLogLvl() == nil {
+			v.Addf(path+".Level", "unknown level %q, expected one of: %s, %s, %s, %s", lvl,
+				catlog.LevelDebugStr, catlog.LevelInfoStr, catlog.LevelWarnStr, catlog.LevelErrorStr)
+		}
+	default:
+		v.Addf(path+".Level", "must be string or int, got %T", lvl)
+	}
+}
+
+func validateDB(v *Validation, path string, cfg catdb.ConfigDB) {
+	if reflect.ValueOf(cfg).IsZero() {
+		return // section is not used
+	}
+	if cfg.DSN == "" {
+		if cfg.Host == "" {
+			v.Addf(path+".Host", "required if DSN is not set")
+		}
+		if cfg.Port == 0 {
+			v.Addf(path+".Port", "required if DSN is not set")
+		}
+		if cfg.Name == "" {
+			v.Addf(path+".Name", "required if DSN is not set")
+		}
+		if cfg.User == "" {
+			v.Addf(path+".User", "required if DSN is not set")
+		}
+	}
+
+	validatePositiveDuration(v, path+".ConnectTimeout", cfg.ConnectTimeout)
+	switch cfg.TargetSessionAttrs {
+	case "", "any", "read-write", "read-only", "primary", "standby", "prefer-standby":
+	default:
+		v.Addf(path+".TargetSessionAttrs", "unknown value %q", cfg.TargetSessionAttrs)
+	}
+
+	for i, replica := range cfg.Replicas {
+		if replica.DSN == "" && replica.Host == "" {
+			v.Addf(fmt.Sprintf("%s.Replicas[%d].Host", path, i), "required if DSN is not set")
+		}
+	}
+	validatePositiveDuration(v, path+".ReplicaCheckPeriod", cfg.ReplicaCheckPeriod)
+
+	tls := cfg.ConfigTLS
+	switch tls.SSLMode {
+	case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		v.Addf(path+".TLS.SSLMode", "unknown value %q, expected one of: disable, allow, prefer, require, verify-ca, verify-full", tls.SSLMode)
+	}
+	if tls.Cert != "" && tls.Key == "" {
+		v.Addf(path+".TLS.Key", "required if Cert is set")
+	}
+	if tls.Key != "" && tls.Cert == "" {
+		v.Addf(path+".TLS.Cert", "required if Key is set")
+	}
+	if tls.ServerName != "" && tls.SSLMode != "verify-full" {
+		v.Addf(path+".TLS.ServerName", "used only with verify-full sslmode")
+	}
+
+	validatePositiveDuration(v, path+".Health.Timeout", cfg.ConfigHealth.Timeout)
+	validatePositiveDuration(v, path+".Health.MaxAcquireWait", cfg.MaxAcquireWait)
+	if r := cfg.SaturationRatio; r < 0 || r > 1 {
+		v.Addf(path+".Health.SaturationRatio", "must be in range 0-1, got %v", r)
+	}
+
+	validatePositiveDuration(v, path+".Trace.SlowQuery", cfg.SlowQuery)
+	if r := cfg.SampleRate; r < 0 || r > 1 {
+		v.Addf(path+".Trace.SampleRate", "must be in range 0-1, got %v", r)
+	}
+	if cfg.MaxArgLen < -1 {
+		v.Addf(path+".Trace.MaxArgLen", "must be positive or -1, got %d", cfg.MaxArgLen)
+	}
+	if cfg.MaxSQLLen < 0 {
+		v.Addf(path+".Trace.MaxSQLLen", "must not be negative, got %d", cfg.MaxSQLLen)
+	}
+
+	pool := cfg.ConfigPool
+	validatePositiveDuration(v, path+".Pool.MaxConnLifetime", pool.MaxConnLifetime)
+	validatePositiveDuration(v, path+".Pool.MaxConnIdleTime", pool.MaxConnIdleTime)
+	validatePositiveDuration(v, path+".Pool.HealthCheckPeriod", pool.HealthCheckPeriod)
+	if pool.MaxConnLifetimeJitter != nil && *pool.MaxConnLifetimeJitter < 0 {
+		v.Addf(path+".Pool.MaxConnLifetimeJitter", "must not be negative, got %s", *pool.MaxConnLifetimeJitter)
+	}
+	if pool.MaxConns != nil && *pool.MaxConns < 1 {
+		v.Addf(path+".Pool.MaxConns", "must be positive, got %d", *pool.MaxConns)
+	}
+	if pool.MinConns != nil && *pool.MinConns < 0 {
+		v.Addf(path+".Pool.MinConns", "must not be negative, got %d", *pool.MinConns)
+	}
+	if pool.MaxConns != nil && pool.MinConns != nil && *pool.MinConns > *pool.MaxConns {
+		v.Addf(path+".Pool.MinConns", "must not exceed MaxConns %d, got %d", *pool.MaxConns, *pool.MinConns)
+	}
+}
+
+func validatePositiveDuration(v *Validation, path string, d *time.Duration) {
+	if d != nil && *d <= 0 {
+		v.Addf(path, "must be positive, got %s", *d)
+	}
+}
+
+func validateKafka(v *Validation, path string, cfg map[string]catkafka.ConfigKafka) {
+	tags := make([]string, 0, len(cfg))
+	for tag := range cfg {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	for _, tag := range tags {
+		tagPath := path + "." + tag
+		tagCfg := cfg[tag]
+		prop := func(k string) string {
+			return tagPath + "[" + k + "]"
+		}
+
+		switch tagCfg.Role {
+		case "", catkafka.RoleProducer:
+		case catkafka.RoleConsumer:
+			switch topics := tagCfg.GetTopics(); {
+			case len(topics) == 0:
+				v.Addf(tagPath+".topics", "required for %s role", catkafka.RoleConsumer)
+			case len(topics) > 1:
+				v.Addf(tagPath+".topics", "single topic is supported for %s role, got %v", catkafka.RoleConsumer, topics)
+			}
+		default:
+			v.Addf(tagPath+".role", "unknown role %q, expected one of: %s, %s",
+				tagCfg.Role, catkafka.RoleProducer, catkafka.RoleConsumer)
+		}
+		for i, topic := range tagCfg.Topics {
+			if topic == "" {
+				v.Addf(fmt.Sprintf("%s.topics[%d]", tagPath, i), "must be non-empty")
+			}
+		}
+		switch tagCfg.PartitionHash {
+		case "", catkafka.PartitionHashCRC32, catkafka.PartitionHashXXHash, catkafka.PartitionHashRR:
+		default:
+			v.Addf(tagPath+".partition.hash", "unknown partition hash %q, expected one of: %s, %s, %s",
+				tagCfg.PartitionHash, catkafka.PartitionHashCRC32, catkafka.PartitionHashXXHash, catkafka.PartitionHashRR)
+		}
+		if tagCfg.FlushTimeoutMs < 0 {
+			v.Addf(tagPath+".flush.timeout.ms", "must be non-negative, got %d", tagCfg.FlushTimeoutMs)
+		}
+		validateRetry(v, tagPath+".retry", tagCfg.Retry)
+		if tagCfg.DLQ.Topic != "" && tagCfg.DLQ.Topic == tagCfg.Topic {
+			v.Addf(tagPath+".dlq.topic", "must differ from topic %q", tagCfg.Topic)
+		}
+
+		cm := tagCfg.ConfigMap()
+		if servers, ok := cm["bootstrap.servers"].(string); !ok || servers == "" {
+			v.Addf(prop("bootstrap.servers"), "required non-empty string")
+		}
+		if val, ok := cm["go.logs.channel.enable"]; ok {
+			if _, isBool := val.(bool); !isBool {
+				v.Addf(prop("go.logs.channel.enable"), "must be bool, got %v", val)
+			}
+		}
+		if val, ok := cm["log_level"]; ok {
+			if lvl, isInt := val.(int); !isInt || lvl < 0 || lvl > 7 {
+				v.Addf(prop("log_level"), "must be int in range 0-7, got %v", val)
+			}
+		}
+	}
+}
+
+func validateRetry(v *Validation, path string, retry catkafka.ConfigRetry) {
+	if retry.Attempts < 0 {
+		v.Addf(path+".attempts", "must be non-negative, got %d", retry.Attempts)
+	}
+	if retry.Backoff < 0 {
+		v.Addf(path+".backoff", "must be non-negative, got %s", retry.Backoff)
+	}
+	if retry.MaxBackoff != 0 && retry.MaxBackoff < retry.Backoff {
+		v.Addf(path+".max.backoff", "must not be less than backoff %s, got %s", retry.Backoff, retry.MaxBackoff)
+	}
+}
@@ -0,0 +1,313 @@
+package catcfg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/surkovvs/gocat/catlog"
+)
+
+const defaultReloadDebounce = 100 * time.Millisecond
+
+// Watcher keeps the last valid config and reloads it on changes of
+// watched files. Snapshots are shared between subscribers and must not be modified,
+// snapshot is deep copied, so it shares no maps, slices or pointers with loader
+// sources and other snapshots.
+// Watcher implements Runner and Shutdowner, so it could be added to catapp.
+type Watcher[T any] struct {
+	loader   *Loader
+	current  atomic.Pointer[T]
+	reloadMu *sync.Mutex
+	mu       *sync.Mutex
+	subs     map[int]func(old, new *T)
+	nextID   int
+	logger   catlog.Logger
+	debounce time.Duration
+	stopOnce *sync.Once
+	stop     chan struct{}
+}
+
+// Watch loads config and returns watcher for it, watching starts with Run.
+func (l *Loader) Watch() (*Watcher[Config], error) {
+	return newWatcher[Config](l)
+}
+
+// Watch is Load with reloading on changes, see Load for type requirements.
+func Watch[T any](opts ...loaderOption) (*Watcher[T], error) {
+	return newWatcher[T](NewLoader(opts...))
+}
+
+func newWatcher[T any](l *Loader) (*Watcher[T], error) {
+	cfg, err := loadInto[T](l)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher[T]{
+		loader:   l,
+		reloadMu: &sync.Mutex{},
+		mu:       &sync.Mutex{},
+		subs:     make(map[int]func(old, new *T)),
+		logger:   catlog.Nop(),
+		debounce: defaultReloadDebounce,
+		stopOnce: &sync.Once{},
+		stop:     make(chan struct{}),
+	}
+	w.current.Store(detachSnapshot(cfg))
+	return w, nil
+}
+
+// detachSnapshot deep copies config, as values of sources could be decoded
+// into it as is
+func detachSnapshot[T any](cfg *T) *T {
+	return deepCopy(reflect.ValueOf(cfg)).Interface().(*T)
+}
+
+// deepCopy copies maps, slices and pointers of exported fields, interfaces
+// are copied only if they hold maps or slices, so e.g. loggers are kept shared
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type().Elem())
+		res.Elem().Set(deepCopy(v.Elem()))
+		return res
+	case reflect.Struct:
+		res := reflect.New(v.Type()).Elem()
+		res.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := res.Field(i); field.CanSet() {
+				field.Set(deepCopy(v.Field(i)))
+			}
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			res.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return res
+	case reflect.Array:
+		res := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return res
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		if kind := v.Elem().Kind(); kind == reflect.Map || kind == reflect.Slice {
+			res := reflect.New(v.Type()).Elem()
+			res.Set(deepCopy(v.Elem()))
+			return res
+		}
+	}
+	return v
+}
+
+func (w *Watcher[T]) SetLogger(logger catlog.Logger) {
+	w.logger = logger
+}
+
+// Current returns the last valid config snapshot.
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// Subscribe registers fn called with previous and new snapshots on each
+// successful reload, fn must not block for long.
+func (w *Watcher[T]) Subscribe(fn func(old, new *T)) (unsubscribe func()) {
+	w.mu.Lock()
+	id := w.nextID
+	w.nextID++
+	w.subs[id] = fn
+	w.mu.Unlock()
+
+	return func() {
+		w.mu.Lock()
+		delete(w.subs, id)
+		w.mu.Unlock()
+	}
+}
+
+// NotifyLogConfig implements catlog.ConfigNotifier, fn is called
+// only when logger section has been changed.
+func (w *Watcher[T]) NotifyLogConfig(fn func(catlog.Configurer)) (cancel func()) {
+	return w.Subscribe(func(old, new *T) {
+		oldCfg, _ := any(old).(interface{ base() *Config })
+		newCfg, ok := any(new).(interface{ base() *Config })
+		if !ok {
+			return
+		}
+		if oldCfg != nil && reflect.DeepEqual(oldCfg.base().ConfigLog, newCfg.base().ConfigLog) {
+			return
+		}
+		fn(newCfg.base().ConfigLog)
+	})
+}
+
+// Reload loads and validates config, on success new snapshot is published
+// to subscribers, otherwise the last valid config is kept.
+func (w *Watcher[T]) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	cfg, err := loadInto[T](w.loader)
+	if err != nil {
+		return err
+	}
+	cfg = detachSnapshot(cfg)
+	old := w.current.Swap(cfg)
+
+	w.mu.Lock()
+	subs := make([]func(old, new *T), 0, len(w.subs))
+	ids := make([]int, 0, len(w.subs))
+	for id := range w.subs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		subs = append(subs, w.subs[id])
+	}
+	w.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(old, cfg)
+	}
+	return nil
+}
+
+// Run watches files of loader sources until ctx done or Shutdown called.
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf(`new fs watcher: %w`, err)
+	}
+	defer fsw.Close()
+
+	targets := w.targets()
+	if len(targets) == 0 {
+		return errors.New(`there are no sources to watch`)
+	}
+	dirs := make(map[string]struct{})
+	for _, target := range targets {
+		if _, ok := dirs[target.dir]; ok {
+			continue
+		}
+		if err := fsw.Add(target.dir); err != nil {
+			return fmt.Errorf(`watch %s: %w`, target.dir, err)
+		}
+		dirs[target.dir] = struct{}{}
+	}
+
+	realPaths := resolveTargets(targets)
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.stop:
+			return nil
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Warn("config watcher error", "error", err)
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			// symlinks swapping is tracked too, as kubernetes does for mounted configmaps
+			newRealPaths := resolveTargets(targets)
+			if isTargeted(event, targets) || !maps.Equal(realPaths, newRealPaths) {
+				realPaths = newRealPaths
+				timer.Reset(w.debounce)
+			}
+		case <-timer.C:
+			if err := w.Reload(); err != nil {
+				w.logger.Error("config reload rejected, the last valid config is kept",
+					"error", err)
+				continue
+			}
+			w.logger.Info("config reloaded")
+		}
+	}
+}
+
+func (w *Watcher[T]) Shutdown(_ context.Context) error {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	return nil
+}
+
+func (w *Watcher[T]) targets() []watchTarget {
+	var targets []watchTarget
+	sources, _ := w.loader.allSources() // error is reported by reload
+	for _, src := range sources {
+		if wsrc, ok := src.(watchable); ok {
+			targets = append(targets, wsrc.watchTargets()...)
+		}
+	}
+	return targets
+}
+
+func isTargeted(event fsnotify.Event, targets []watchTarget) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) &&
+		!event.Has(fsnotify.Rename) && !event.Has(fsnotify.Remove) {
+		return false
+	}
+	dir, file := filepath.Split(filepath.Clean(event.Name))
+	dir = filepath.Clean(dir)
+	for _, target := range targets {
+		if target.dir != dir {
+			continue
+		}
+		if target.file == file ||
+			(target.file == "" && slices.Contains(configExts, strings.ToLower(filepath.Ext(file)))) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveTargets(targets []watchTarget) map[string]string {
+	res := make(map[string]string, len(targets))
+	for _, target := range targets {
+		if target.file == "" {
+			continue
+		}
+		path := filepath.Join(target.dir, target.file)
+		real, _ := filepath.EvalSymlinks(path)
+		res[path] = real
+	}
+	return res
+}
@@ -0,0 +1,134 @@
+package catcfg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surkovvs/gocat/catlog"
+)
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	write := func(level string) {
+		if err := os.WriteFile(path, []byte("Logger:\n  Level: '"+level+"'\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("info")
+
+	w, err := NewLoader(WithSources(File(path))).Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan [2]*Config, 1)
+	w.Subscribe(func(old, new *Config) {
+		changes <- [2]*Config{old, new}
+	})
+	levels := make(chan catlog.Level, 1)
+	w.NotifyLogConfig(func(cfg catlog.Configurer) {
+		levels <- *cfg.GetLogLvl()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond) // let watcher start
+
+	write("debug")
+	select {
+	case change := <-changes:
+		if *change[0].GetLogLvl() != catlog.LevelInfo || *change[1].GetLogLvl() != catlog.LevelDebug {
+			t.Fatalf("unexpected change: %v -> %v", change[0].Level, change[1].Level)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("config was not reloaded")
+	}
+	if lvl := <-levels; lvl != catlog.LevelDebug {
+		t.Fatalf("unexpected level notified: %v", lvl)
+	}
+
+	write("verbose")
+	select {
+	case change := <-changes:
+		t.Fatalf("invalid config has been published: %v", change[1].Level)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if *w.Current().GetLogLvl() != catlog.LevelDebug {
+		t.Fatal("the last valid config was not kept")
+	}
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherSnapshotsDetached(t *testing.T) {
+	librdkafka := map[string]any{"linger.ms": 5}
+	w, err := NewLoader(WithoutValidation(), WithSources(Defaults(map[string]any{
+		"Kafka": map[string]any{
+			"produser": map[string]any{"librdkafka": librdkafka},
+		},
+	}))).Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Current().Kafka["produser"].Librdkafka["linger.ms"] = 100
+	settings := w.Current().meta.settings["kafka"].(map[string]any)["produser"].(map[string]any)
+	if val := settings["librdkafka"].(map[string]any)["linger.ms"]; val != 5 {
+		t.Fatalf("snapshot shares map with loaded settings, got linger.ms %v", val)
+	}
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if val := w.Current().Kafka["produser"].Librdkafka["linger.ms"]; val != 5 {
+		t.Fatalf("snapshot shares map with previous one, got linger.ms %v", val)
+	}
+	if librdkafka["linger.ms"] != 5 {
+		t.Fatal("source map has been modified")
+	}
+}
+
+func TestWatcherSnapshotsDetachedUserSections(t *testing.T) {
+	type appConfig struct {
+		Config   `mapstructure:",squash"`
+		Features map[string]any `mapstructure:"Features"`
+	}
+	tags := []any{"beta"}
+	w, err := Watch[appConfig](WithoutValidation(), WithSources(Defaults(map[string]any{
+		"Database": map[string]any{
+			"Replicas":      []any{map[string]any{"Host": "replica1"}},
+			"RuntimeParams": map[string]any{"statement_timeout": "1s"},
+		},
+		"Features": map[string]any{"tags": tags},
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := w.Current()
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	cur := w.Current()
+	cur.Replicas[0].Host = "changed"
+	cur.RuntimeParams["statement_timeout"] = "changed"
+	cur.Features["tags"].([]any)[0] = "changed"
+
+	if old.Replicas[0].Host != "replica1" || old.RuntimeParams["statement_timeout"] != "1s" {
+		t.Fatalf("snapshots share database section: %+v", old.Redacted())
+	}
+	if old.Features["tags"].([]any)[0] != "beta" || tags[0] != "beta" {
+		t.Fatal("snapshot shares user section")
+	}
+}
@@ -2,6 +2,11 @@ package catdb
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,31 +20,239 @@ type ConfigPool struct {
 	HealthCheckPeriod     *time.Duration
 }
 
+type ConfigTLS struct {
+	SSLMode    string // disable (default), allow, prefer, require, verify-ca, verify-full; allow, prefer - pgx only
+	RootCert   string // path to CA certificate
+	Cert       string // path to client certificate
+	Key        string // path to client key
+	ServerName string // host name to verify certificate with, if differs from Host
+}
+
+type ConfigTrace struct {
+	Enabled    bool           // LogQueries enables tracing with defaults too
+	SlowQuery  *time.Duration // queries slower than it are logged with Warn
+	SampleRate float64        // share of successful fast queries to log, 1 if not set
+	RedactArgs bool           // args are masked, e.g. if queries contain personal data
+	MaxArgLen  int            // longer args are truncated, 64 if not set, -1 disables truncation
+	MaxSQLLen  int            // longer SQL is truncated, 0 disables truncation
+	LogConnect bool
+	LogPrepare bool
+}
+
+// TraceEnabled reports if queries should be traced.
+func (cfg ConfigDB) TraceEnabled() bool {
+	return cfg.LogQueries || cfg.ConfigTrace.Enabled
+}
+
 type ConfigDB struct {
-	// prefer URL for coonection
+	// prefer URL for coonection, connection options below are not applied to it
 	DSN        string
 	Host       string
 	Port       uint16
 	Name       string
 	User       string
 	Pass       string
-	LogQueries bool
+	LogQueries bool // kept for compatibility, use Trace section
+
+	ApplicationName    string
+	ConnectTimeout     *time.Duration // rounded up to seconds
+	SearchPath         string
+	TargetSessionAttrs string            // any, read-write, read-only, primary, standby, prefer-standby; pgx v3 - any, read-write only; not for lib/pq
+	RuntimeParams      map[string]string // sent to server on connect, e.g. statement_timeout
+
+	ConfigPool   `mapstructure:"Pool"`
+	ConfigTLS    `mapstructure:"TLS"`
+	ConfigHealth `mapstructure:"Health"`
+	ConfigTrace  `mapstructure:"Trace"`
+
+	Replicas           []ConfigReplica
+	ReplicaCheckPeriod *time.Duration // 5s if not set
+}
+
+// ConfigReplica is read replica connection, options which are not
+// set here are inherited from the primary.
+type ConfigReplica struct {
+	DSN  string
+	Host string
+	Port uint16
+}
+
+// ReplicaConfigs returns connection configs of replicas. Replica set by host
+// inherits options of the primary, DSN of the primary is used with host and port
+// replaced. Target session attrs are not inherited, as e.g. read-write fails on replica.
+func (cfg ConfigDB) ReplicaConfigs() ([]ConfigDB, error) {
+	res := make([]ConfigDB, 0, len(cfg.Replicas))
+	for i, replica := range cfg.Replicas {
+		rcfg := cfg
+		rcfg.Replicas = nil
+		rcfg.TargetSessionAttrs = ""
+		switch {
+		case replica.DSN != "":
+			rcfg.DSN = replica.DSN
+		case cfg.DSN != "":
+			dsn, err := replaceDSNHost(cfg.DSN, replica.Host, replica.Port)
+			if err != nil {
+				return nil, fmt.Errorf("replica %d: %w", i, err)
+			}
+			rcfg.DSN = dsn
+		}
+		if replica.Host != "" {
+			rcfg.Host = replica.Host
+		}
+		if replica.Port != 0 {
+			rcfg.Port = replica.Port
+		}
+		res = append(res, rcfg)
+	}
+	return res, nil
+}
+
+// replaceDSNHost sets host and port (if not 0) of URL or keyword/value connection
+// string and drops target_session_attrs
+func replaceDSNHost(dsn, host string, port uint16) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("parse DSN: %w", err)
+		}
+		hostPort := host
+		switch {
+		case port != 0:
+			hostPort = net.JoinHostPort(host, strconv.Itoa(int(port)))
+		case u.Port() != "" && !strings.Contains(u.Host, ","):
+			hostPort = net.JoinHostPort(host, u.Port())
+		}
+		u.Host = hostPort
+		query := u.Query()
+		query.Del("target_session_attrs")
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
 
-	ConfigPool `mapstructure:"Pool"`
+	params, err := parseDSNParams(dsn)
+	if err != nil {
+		return "", err
+	}
+	sb := strings.Builder{}
+	add := func(key, val string) {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(key + "=" + QuoteDSNValue(val))
+	}
+	add("host", host)
+	if port != 0 {
+		add("port", strconv.Itoa(int(port)))
+	}
+	for i := 0; i < len(params); i += 2 {
+		switch key := params[i]; {
+		case key == "host", key == "hostaddr", key == "target_session_attrs", key == "port" && port != 0:
+		default:
+			add(key, params[i+1])
+		}
+	}
+	return sb.String(), nil
+}
 
-	// TLS *tls.Config
+// parseDSNParams returns keys and values of keyword/value connection string
+func parseDSNParams(dsn string) ([]string, error) {
+	var params []string
+	s := strings.TrimSpace(dsn)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("parse DSN: missing = after %q", s)
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t\n")
+
+		var val strings.Builder
+		if strings.HasPrefix(s, "'") {
+			closed := false
+			i := 1
+			for ; i < len(s); i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					val.WriteByte(s[i])
+					continue
+				}
+				if s[i] == '\'' {
+					closed = true
+					break
+				}
+				val.WriteByte(s[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("parse DSN: unterminated quoted value of %s", key)
+			}
+			s = s[i+1:]
+		} else {
+			end := strings.IndexAny(s, " \t\n")
+			if end < 0 {
+				end = len(s)
+			}
+			val.WriteString(s[:end])
+			s = s[end:]
+		}
+		params = append(params, key, val.String())
+		s = strings.TrimSpace(s)
+	}
+	return params, nil
 }
 
-// TODO: add TLS
+// GetDSN returns DSN if it set, otherwise builds keyword/value connection string,
+// values are quoted, so passwords with spaces and quotes are passed as is.
 func (cfg ConfigDB) GetDSN() string {
 	if cfg.DSN != "" {
 		return cfg.DSN
 	}
-	return fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=disable",
-		cfg.Host,
-		cfg.Port,
-		cfg.Name,
-		cfg.User,
-		cfg.Pass,
-	)
+
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	params := []string{
+		"host", cfg.Host,
+		"port", strconv.Itoa(int(cfg.Port)),
+		"dbname", cfg.Name,
+		"user", cfg.User,
+		"password", cfg.Pass,
+		"sslmode", sslMode,
+		"sslrootcert", cfg.RootCert,
+		"sslcert", cfg.Cert,
+		"sslkey", cfg.Key,
+		"application_name", cfg.ApplicationName,
+		"search_path", cfg.SearchPath,
+		"target_session_attrs", cfg.TargetSessionAttrs,
+	}
+	if cfg.ConnectTimeout != nil && *cfg.ConnectTimeout > 0 {
+		secs := (*cfg.ConnectTimeout + time.Second - 1) / time.Second
+		params = append(params, "connect_timeout", strconv.Itoa(int(secs)))
+	}
+	keys := make([]string, 0, len(cfg.RuntimeParams))
+	for key := range cfg.RuntimeParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		params = append(params, key, cfg.RuntimeParams[key])
+	}
+
+	sb := strings.Builder{}
+	for i := 0; i < len(params); i += 2 {
+		key, val := params[i], params[i+1]
+		if val == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(key + "=" + QuoteDSNValue(val))
+	}
+	return sb.String()
+}
+
+// QuoteDSNValue quotes value of keyword/value connection string
+func QuoteDSNValue(val string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(val) + "'"
 }
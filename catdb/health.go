@@ -0,0 +1,87 @@
+package catdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrDegraded is returned by healthchecks of connectors, if database is
+// available, but pool is saturated.
+var ErrDegraded = errors.New("degraded")
+
+const (
+	defaultHealthQuery           = "SELECT 1"
+	defaultHealthTimeout         = time.Second
+	defaultHealthSaturationRatio = 0.9
+)
+
+type ConfigHealth struct {
+	Query           string         // probe query, SELECT 1 if not set
+	Timeout         *time.Duration // probe timeout, 1s if not set
+	SaturationRatio float64        // share of acquired conns of max reported as degraded, 0.9 if not set
+	MaxAcquireWait  *time.Duration // average wait for conn since previous check reported as degraded
+}
+
+// PoolStats is connector independent pool state, durations and counts are cumulative.
+type PoolStats struct {
+	Acquired     int64
+	Max          int64 // 0 if unlimited
+	WaitCount    int64 // acquires, which waited for conn
+	WaitDuration time.Duration
+}
+
+// HealthProbe runs probe query and checks pool saturation.
+type HealthProbe struct {
+	cfg ConfigHealth
+
+	mu       *sync.Mutex
+	lastWait PoolStats
+}
+
+func NewHealthProbe(cfg ConfigHealth) *HealthProbe {
+	if cfg.Query == "" {
+		cfg.Query = defaultHealthQuery
+	}
+	if cfg.Timeout == nil {
+		timeout := defaultHealthTimeout
+		cfg.Timeout = &timeout
+	}
+	if cfg.SaturationRatio <= 0 {
+		cfg.SaturationRatio = defaultHealthSaturationRatio
+	}
+	return &HealthProbe{cfg: cfg, mu: &sync.Mutex{}}
+}
+
+// Check runs query with exec in the probe timeout, then checks stats.
+func (hp *HealthProbe) Check(ctx context.Context, exec func(ctx context.Context, query string) error, stats PoolStats) error {
+	ctx, cancel := context.WithTimeout(ctx, *hp.cfg.Timeout)
+	defer cancel()
+	if err := exec(ctx, hp.cfg.Query); err != nil {
+		return fmt.Errorf("probe query: %w", err)
+	}
+	return hp.checkSaturation(stats)
+}
+
+func (hp *HealthProbe) checkSaturation(stats PoolStats) error {
+	hp.mu.Lock()
+	last := hp.lastWait
+	hp.lastWait = stats
+	hp.mu.Unlock()
+
+	var errs []error
+	if stats.Max > 0 && float64(stats.Acquired) >= hp.cfg.SaturationRatio*float64(stats.Max) {
+		errs = append(errs, fmt.Errorf("%w: %d of %d conns are acquired", ErrDegraded, stats.Acquired, stats.Max))
+	}
+	if hp.cfg.MaxAcquireWait != nil {
+		if waits := stats.WaitCount - last.WaitCount; waits > 0 {
+			avg := (stats.WaitDuration - last.WaitDuration) / time.Duration(waits)
+			if avg > *hp.cfg.MaxAcquireWait {
+				errs = append(errs, fmt.Errorf("%w: average wait for conn is %s", ErrDegraded, avg))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,28 @@
+package catdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthProbeSaturation(t *testing.T) {
+	wait := 10 * time.Millisecond
+	hp := NewHealthProbe(ConfigHealth{MaxAcquireWait: &wait})
+	exec := func(context.Context, string) error { return nil }
+	ctx := context.Background()
+
+	if err := hp.Check(ctx, exec, PoolStats{Acquired: 5, Max: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := hp.Check(ctx, exec, PoolStats{Acquired: 9, Max: 10}); !errors.Is(err, ErrDegraded) {
+		t.Fatalf("degraded expected for saturation, got %v", err)
+	}
+	if err := hp.Check(ctx, exec, PoolStats{WaitCount: 2, WaitDuration: time.Second}); !errors.Is(err, ErrDegraded) {
+		t.Fatalf("degraded expected for acquire wait, got %v", err)
+	}
+	if err := hp.Check(ctx, exec, PoolStats{WaitCount: 2, WaitDuration: time.Second}); err != nil {
+		t.Fatalf("no new waits, got %v", err)
+	}
+}
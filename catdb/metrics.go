@@ -0,0 +1,32 @@
+package catdb
+
+import (
+	"database/sql"
+
+	"github.com/surkovvs/gocat/catmetrics"
+)
+
+// CollectDBStats writes database/sql pool stats, connection gauges share
+// names with pgx pool metrics.
+func CollectDBStats(w *catmetrics.Writer, stats sql.DBStats, labels ...catmetrics.Label) {
+	w.Gauge("gocat_db_pool_acquired_conns", "Connections in use.", float64(stats.InUse), labels...)
+	w.Gauge("gocat_db_pool_idle_conns", "Idle connections.", float64(stats.Idle), labels...)
+	w.Gauge("gocat_db_pool_total_conns", "Open connections.", float64(stats.OpenConnections), labels...)
+	w.Gauge("gocat_db_pool_max_conns", "Maximum of open connections.", float64(stats.MaxOpenConnections), labels...)
+	w.Counter("gocat_db_pool_wait_total", "Connection requests which waited for connection.",
+		float64(stats.WaitCount), labels...)
+	w.Counter("gocat_db_pool_wait_duration_seconds_total", "Time spent waiting for connections.",
+		stats.WaitDuration.Seconds(), labels...)
+	closed := []struct {
+		reason string
+		count  int64
+	}{
+		{"max_idle", stats.MaxIdleClosed},
+		{"max_idle_time", stats.MaxIdleTimeClosed},
+		{"max_lifetime", stats.MaxLifetimeClosed},
+	}
+	for _, c := range closed {
+		w.Counter("gocat_db_pool_closed_total", "Connections closed by pool limits.", float64(c.count),
+			append(labels[:len(labels):len(labels)], catmetrics.Label{Name: "reason", Value: c.reason})...)
+	}
+}
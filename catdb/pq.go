@@ -0,0 +1,34 @@
+package catdb
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Options, which lib/pq does not support, ServerName as lib/pq always
+// verifies certificate with host name.
+var (
+	ErrServerNameUnsupported         = errors.New("TLS server name is not supported by lib/pq driver")
+	ErrSSLModeUnsupported            = errors.New("sslmode is not supported by lib/pq driver, use disable, require, verify-ca or verify-full")
+	ErrTargetSessionAttrsUnsupported = errors.New("target session attrs are not supported by lib/pq driver")
+)
+
+// CheckPQOptions rejects options, which lib/pq does not support, so they are
+// reported on creation instead of connection failure. Options are not used
+// with DSN, so nothing is checked if it is set.
+func (cfg ConfigDB) CheckPQOptions() error {
+	if cfg.DSN != "" {
+		return nil
+	}
+	if cfg.ServerName != "" {
+		return ErrServerNameUnsupported
+	}
+	switch cfg.SSLMode {
+	case "allow", "prefer":
+		return fmt.Errorf("%w: %s", ErrSSLModeUnsupported, cfg.SSLMode)
+	}
+	if cfg.TargetSessionAttrs != "" {
+		return fmt.Errorf("%w: %s", ErrTargetSessionAttrsUnsupported, cfg.TargetSessionAttrs)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package catdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+type IsoLevel string
+
+const (
+	IsoSerializable    IsoLevel = "serializable"
+	IsoRepeatableRead  IsoLevel = "repeatable read"
+	IsoReadCommitted   IsoLevel = "read committed"
+	IsoReadUncommitted IsoLevel = "read uncommitted"
+)
+
+const (
+	SQLStateSerializationFailure = "40001"
+	SQLStateDeadlockDetected     = "40P01"
+
+	defaultTxAttempts   = 3
+	defaultTxBackoff    = 10 * time.Millisecond
+	defaultTxMaxBackoff = time.Second
+)
+
+// TxOptions of transaction helpers, zero value is transaction with server default
+// isolation level retried up to 3 times on serialization failures and deadlocks.
+type TxOptions struct {
+	Isolation   IsoLevel
+	ReadOnly    bool
+	MaxAttempts int           // including the first one, 3 if not set, 1 disables retries
+	Backoff     time.Duration // before the first retry, doubled on each one, 10ms if not set
+	MaxBackoff  time.Duration // 1s if not set
+}
+
+// SQL returns isolation level for database/sql.
+func (lvl IsoLevel) SQL() sql.IsolationLevel {
+	switch lvl {
+	case IsoSerializable:
+		return sql.LevelSerializable
+	case IsoRepeatableRead:
+		return sql.LevelRepeatableRead
+	case IsoReadCommitted:
+		return sql.LevelReadCommitted
+	case IsoReadUncommitted:
+		return sql.LevelReadUncommitted
+	default:
+		return sql.LevelDefault
+	}
+}
+
+// IsRetryable reports if transaction failed with serialization failure or deadlock,
+// errors of pgx and lib/pq are recognized by SQLState method.
+func IsRetryable(err error) bool {
+	var stateErr interface{ SQLState() string }
+	if !errors.As(err, &stateErr) {
+		return false
+	}
+	switch stateErr.SQLState() {
+	case SQLStateSerializationFailure, SQLStateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
+// RunTx calls attempt until it succeeds, fails with not retryable error,
+// attempts are exceeded or ctx is done. attempt must run whole transaction.
+func RunTx(ctx context.Context, opts TxOptions, attempt func(ctx context.Context) error) error {
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultTxAttempts
+	}
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = defaultTxBackoff
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultTxMaxBackoff
+	}
+
+	for i := 1; ; i++ {
+		err := attempt(ctx)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+		if i >= maxAttempts {
+			return fmt.Errorf("transaction failed after %d attempts: %w", i, err)
+		}
+
+		delay := backoff + time.Duration(rand.Int63n(int64(backoff/2)+1)) // jitter
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
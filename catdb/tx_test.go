@@ -0,0 +1,59 @@
+package catdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type stateErr string
+
+func (e stateErr) Error() string    { return "sqlstate " + string(e) }
+func (e stateErr) SQLState() string { return string(e) }
+
+func TestRunTx(t *testing.T) {
+	ctx := context.Background()
+	opts := TxOptions{Backoff: time.Millisecond}
+
+	calls := 0
+	err := RunTx(ctx, opts, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("commit: %w", stateErr(SQLStateSerializationFailure))
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("got err %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = RunTx(ctx, opts, func(context.Context) error {
+		calls++
+		return stateErr(SQLStateDeadlockDetected)
+	})
+	if !IsRetryable(err) || calls != defaultTxAttempts {
+		t.Fatalf("got err %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	errUnique := stateErr("23505")
+	err = RunTx(ctx, opts, func(context.Context) error {
+		calls++
+		return errUnique
+	})
+	if !errors.Is(err, errUnique) || calls != 1 {
+		t.Fatalf("got err %v after %d calls", err, calls)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = RunTx(cctx, TxOptions{MaxAttempts: 10, Backoff: time.Hour}, func(context.Context) error {
+		return stateErr(SQLStateSerializationFailure)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("context error expected, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catkafka"
 	"github.com/surkovvs/gocat/catlog"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
@@ -17,15 +18,18 @@ type consumer struct {
 	topic   *string
 	kcons   *kafka.Consumer
 	cfg     *kafka.ConfigMap
+	kcfg    catkafka.ConfigKafka
 	clients map[int32]ConsClient
 }
 
 func New(cfg *catcfg.Config, tag string) *consumer {
 	consCfg := cfg.Kafka[tag]
-	toDefault(consCfg)
+	rdCfg := consCfg.ConfigMap()
+	toDefault(rdCfg)
 	cons := &consumer{
 		logger:  cfg.Logger,
-		cfg:     &consCfg,
+		cfg:     &rdCfg,
+		kcfg:    consCfg,
 		clients: make(map[int32]ConsClient),
 	}
 	return cons
@@ -38,8 +42,20 @@ func toDefault(cfg kafka.ConfigMap) {
 }
 
 func (cons *consumer) Init(ctx context.Context) error {
-	if err := cons.topicCatch(); err != nil {
-		return fmt.Errorf("catching topic: %w", err)
+	if role := cons.kcfg.Role; role != "" && role != catkafka.RoleConsumer {
+		return fmt.Errorf("config with role %v could not be used for consumer", role)
+	}
+	topics := cons.kcfg.GetTopics()
+	if len(topics) == 0 {
+		return errors.New("topic has not been setted for consumer")
+	}
+	if len(topics) > 1 {
+		return fmt.Errorf("consumer supports single topic, got %v", topics)
+	}
+	cons.topic = &topics[0]
+	if cons.logger != nil {
+		cons.logger.Debug("topic has been setted for consumer",
+			"topic", *cons.topic)
 	}
 
 	kcons, err := kafka.NewConsumer(cons.cfg)
@@ -64,7 +80,7 @@ func (cons *consumer) logging(kclient *kafka.Consumer) error {
 	if err != nil {
 		return err
 	}
-	if logEnabled.(bool) {
+	if enabled, _ := logEnabled.(bool); enabled {
 		if cons.logger == nil {
 			return errors.New("logger has not been setted")
 		}
@@ -74,8 +90,8 @@ func (cons *consumer) logging(kclient *kafka.Consumer) error {
 		if err != nil {
 			return err
 		}
-		if logLevelVal != nil {
-			logLevel = logLevelVal.(int)
+		if lvl, ok := logLevelVal.(int); ok {
+			logLevel = lvl
 		}
 		go func() {
 			for event := range kclient.Logs() {
@@ -167,26 +183,6 @@ func (cons *consumer) fetchMeta(ctx context.Context) error {
 	return eg.Wait()
 }
 
-func (cons *consumer) topicCatch() error {
-	val, err := cons.cfg.Get("topic", nil)
-	if err != nil {
-		return err
-	}
-	if val != nil {
-		defer delete(*cons.cfg, "topic")
-		topic, ok := val.(string)
-		if !ok {
-			return errors.New("incorrect value for topic key, must be string")
-		}
-		cons.topic = &topic
-		if cons.logger != nil {
-			cons.logger.Debug("topic has been setted for consumer",
-				"topic", cons.topic)
-		}
-	}
-	return nil
-}
-
 func (cons consumer) Shutdown(ctx context.Context) error {
 	var errs []error
 	if err := cons.kcons.Close(); err != nil {
@@ -7,6 +7,7 @@ import (
 type ProdClient struct {
 	topic     *string
 	partition *int32
+	noRetry   bool
 	prod      *producer
 }
 
@@ -24,16 +25,32 @@ func (client ProdClient) IsProducerStopped() bool {
 	return client.prod.isStopped()
 }
 
+// IsSync reports if Produce returns after delivery has been confirmed.
+func (client ProdClient) IsSync() bool {
+	return client.prod.sync
+}
+
+// HasPartitioner reports whether partition could be selected by key,
+// otherwise Partition must be set.
+func (client ProdClient) HasPartitioner() bool {
+	return client.prod.partitioner != nil
+}
+
+// DLQTopic returns DLQ topic of config, empty if it is not set.
+func (client ProdClient) DLQTopic() string {
+	return client.prod.kcfg.DLQ.Topic
+}
+
 func (client ProdClient) Produce(ctx context.Context, key, value []byte) error {
 	switch {
 	case client.topic != nil && client.partition != nil:
-		return client.prod.produceInTopicPartition(ctx, *client.topic, *client.partition, key, value)
+		return client.prod.produceInTopicPartition(ctx, *client.topic, *client.partition, key, value, client.noRetry)
 	case client.topic != nil:
-		return client.prod.produceInTopic(ctx, *client.topic, key, value)
+		return client.prod.produceInTopic(ctx, *client.topic, key, value, client.noRetry)
 	case client.partition != nil:
-		return client.prod.produceInPartition(ctx, *client.partition, key, value)
+		return client.prod.produceInPartition(ctx, *client.partition, key, value, client.noRetry)
 	default:
-		return client.prod.produce(ctx, key, value)
+		return client.prod.produce(ctx, key, value, client.noRetry)
 	}
 }
 
@@ -41,6 +58,7 @@ func (client ProdClient) Topic(topic string) ProdClient {
 	return ProdClient{
 		topic:     &topic,
 		partition: client.partition,
+		noRetry:   client.noRetry,
 		prod:      client.prod,
 	}
 }
@@ -49,6 +67,18 @@ func (client ProdClient) Partition(part int32) ProdClient {
 	return ProdClient{
 		topic:     client.topic,
 		partition: &part,
+		noRetry:   client.noRetry,
+		prod:      client.prod,
+	}
+}
+
+// NoRetry returns client, which delivers messages of sync producer in single
+// attempt and does not send undelivered ones to DLQ, so caller retries them.
+func (client ProdClient) NoRetry() ProdClient {
+	return ProdClient{
+		topic:     client.topic,
+		partition: client.partition,
+		noRetry:   true,
 		prod:      client.prod,
 	}
 }
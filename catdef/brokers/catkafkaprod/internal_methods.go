@@ -3,6 +3,7 @@ package catkafkaprod
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync/atomic"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
@@ -30,7 +31,7 @@ func (prod *producer) sendSyncMsg(msg contextedMsg) error {
 	}
 }
 
-func (prod *producer) produce(ctx context.Context, key, value []byte) error {
+func (prod *producer) produce(ctx context.Context, key, value []byte, noRetry bool) error {
 	if prod.topic == nil {
 		return errors.New("topic has not been setted, set it in config, or use SyncProduceInTopic, SyncProduceInTopicPartition")
 	}
@@ -38,16 +39,21 @@ func (prod *producer) produce(ctx context.Context, key, value []byte) error {
 		return errors.New("function for partition seleting has not been setted, set it with options or use SyncProduceInPartition, SyncProduceInTopicPartition")
 	}
 
+	partNum, err := prod.partitions(*prod.topic)
+	if err != nil {
+		return err
+	}
 	part, err := prod.partitioner.PartHash(key)
 	if err != nil {
 		return err
 	}
 	ctxMsg := contextedMsg{
-		ctx: ctx,
+		ctx:     ctx,
+		noRetry: noRetry,
 		msg: &kafka.Message{
 			TopicPartition: kafka.TopicPartition{
 				Topic:     prod.topic,
-				Partition: int32(part % *prod.partNum[*prod.topic]),
+				Partition: int32(part % partNum),
 			},
 			Value: value,
 			Key:   key,
@@ -60,12 +66,13 @@ func (prod *producer) produce(ctx context.Context, key, value []byte) error {
 	return prod.sendAsyncMsg(ctxMsg)
 }
 
-func (prod *producer) produceInPartition(ctx context.Context, part int32, key, value []byte) error {
+func (prod *producer) produceInPartition(ctx context.Context, part int32, key, value []byte, noRetry bool) error {
 	if prod.topic == nil {
 		return errors.New("topic has not been setted, set it in config, or use SyncProduceInTopic, SyncProduceInTopicPartition")
 	}
 	ctxMsg := contextedMsg{
-		ctx: ctx,
+		ctx:     ctx,
+		noRetry: noRetry,
 		msg: &kafka.Message{
 			TopicPartition: kafka.TopicPartition{
 				Topic:     prod.topic,
@@ -82,21 +89,26 @@ func (prod *producer) produceInPartition(ctx context.Context, part int32, key, v
 	return prod.sendAsyncMsg(ctxMsg)
 }
 
-func (prod *producer) produceInTopic(ctx context.Context, topic string, key, value []byte) error {
+func (prod *producer) produceInTopic(ctx context.Context, topic string, key, value []byte, noRetry bool) error {
 	if prod.partitioner == nil {
 		return errors.New("function for partition seleting has not been setted, set it with options or use SyncProduceInPartition, SyncProduceInTopicPartition")
 	}
 
+	partNum, err := prod.partitions(topic)
+	if err != nil {
+		return err
+	}
 	part, err := prod.partitioner.PartHash(key)
 	if err != nil {
 		return err
 	}
 	ctxMsg := contextedMsg{
-		ctx: ctx,
+		ctx:     ctx,
+		noRetry: noRetry,
 		msg: &kafka.Message{
 			TopicPartition: kafka.TopicPartition{
 				Topic:     &topic,
-				Partition: int32(part % *prod.partNum[topic]),
+				Partition: int32(part % partNum),
 			},
 			Value: value,
 			Key:   key,
@@ -109,9 +121,10 @@ func (prod *producer) produceInTopic(ctx context.Context, topic string, key, val
 	return prod.sendAsyncMsg(ctxMsg)
 }
 
-func (prod *producer) produceInTopicPartition(ctx context.Context, topic string, part int32, key, value []byte) error {
+func (prod *producer) produceInTopicPartition(ctx context.Context, topic string, part int32, key, value []byte, noRetry bool) error {
 	ctxMsg := contextedMsg{
-		ctx: ctx,
+		ctx:     ctx,
+		noRetry: noRetry,
 		msg: &kafka.Message{
 			TopicPartition: kafka.TopicPartition{
 				Topic:     &topic,
@@ -128,6 +141,15 @@ func (prod *producer) produceInTopicPartition(ctx context.Context, topic string,
 	return prod.sendAsyncMsg(ctxMsg)
 }
 
+// partitions returns number of partitions of topic fetched on Init.
+func (prod *producer) partitions(topic string) (uint32, error) {
+	partNum, ok := prod.partNum[topic]
+	if !ok || *partNum == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownTopic, topic)
+	}
+	return *partNum, nil
+}
+
 func (prod *producer) stop() error {
 	if atomic.CompareAndSwapUint32(&prod.status, 0, 1) {
 		toClose := prod.msgs
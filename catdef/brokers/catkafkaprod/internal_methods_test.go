@@ -0,0 +1,21 @@
+package catkafkaprod
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestProduceInUnknownTopic(t *testing.T) {
+	partNum := uint32(3)
+	prod := &producer{
+		partNum: map[string]*uint32{"orders": &partNum},
+		sync:    true,
+	}
+	PartWithCrc32Hash(nil)(prod)
+
+	client := prod.NewClient()
+	if err := client.Topic("payments").Produce(context.Background(), []byte("key"), nil); !errors.Is(err, ErrUnknownTopic) {
+		t.Fatalf("ErrUnknownTopic expected, got %v", err)
+	}
+}
@@ -4,18 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catkafka"
 	"github.com/surkovvs/gocat/catlog"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
 
-var ErrProducerIsStopped = errors.New("producer has been stopped")
+var (
+	ErrProducerIsStopped = errors.New("producer has been stopped")
+	ErrUnknownTopic      = errors.New("topic is not found in cluster metadata")
+)
 
 type contextedMsg struct {
-	ctx context.Context
-	msg *kafka.Message
+	ctx     context.Context
+	msg     *kafka.Message
+	noRetry bool // single attempt without DLQ
 }
 
 type producer struct {
@@ -24,6 +30,7 @@ type producer struct {
 	partitioner partitioner
 	partNum     map[string]*uint32
 	cfg         *kafka.ConfigMap
+	kcfg        catkafka.ConfigKafka
 	kprod       *kafka.Producer
 	msgs        chan contextedMsg
 	logs        chan kafka.LogEvent
@@ -36,13 +43,27 @@ type producer struct {
 
 func New(cfg *catcfg.Config, tag string, sync bool, opts ...prodOptions) *producer {
 	prodCfg := cfg.Kafka[tag]
+	rdCfg := prodCfg.ConfigMap()
 	prod := &producer{
-		logger:  cfg.Logger,
-		cfg:     &prodCfg,
-		msgs:    make(chan contextedMsg),
-		errChan: make(chan error),
-		stopped: make(chan struct{}),
-		sync:    sync,
+		logger:      cfg.Logger,
+		cfg:         &rdCfg,
+		kcfg:        prodCfg,
+		msgs:        make(chan contextedMsg),
+		errChan:     make(chan error),
+		stopped:     make(chan struct{}),
+		fushTimeout: prodCfg.FlushTimeoutMs,
+		sync:        sync || prodCfg.Sync,
+	}
+	if prodCfg.Topic != "" {
+		prod.topic = &prodCfg.Topic
+	}
+	switch prodCfg.PartitionHash {
+	case catkafka.PartitionHashCRC32:
+		PartWithCrc32Hash(nil)(prod)
+	case catkafka.PartitionHashXXHash:
+		PartWithxxhash(nil)(prod)
+	case catkafka.PartitionHashRR:
+		PartRR()(prod)
 	}
 
 	for _, opt := range opts {
@@ -57,12 +78,14 @@ func (prod *producer) Init(ctx context.Context) error {
 		return fmt.Errorf("logging: %w", err)
 	}
 
-	if err := prod.topicCatch(); err != nil {
-		return fmt.Errorf("catching topic: %w", err)
+	if role := prod.kcfg.Role; role != "" && role != catkafka.RoleProducer {
+		return fmt.Errorf("config with role %v could not be used for producer", role)
 	}
-
-	if err := prod.flushTomeoutCatch(); err != nil {
-		return fmt.Errorf("catching timeout for flush: %w", err)
+	if prod.logger != nil {
+		prod.logger.Debug("producer has been configured",
+			"topic", prod.kcfg.Topic,
+			"sync", prod.sync,
+			"flush_timeout", prod.fushTimeout)
 	}
 
 	producer, err := kafka.NewProducer(prod.cfg)
@@ -94,7 +117,7 @@ func (prod *producer) logging() error {
 	if err != nil {
 		return err
 	}
-	if logEnabled.(bool) {
+	if enabled, _ := logEnabled.(bool); enabled {
 		if prod.logger == nil {
 			return errors.New("logger has not been setted")
 		}
@@ -109,8 +132,8 @@ func (prod *producer) logging() error {
 		if err != nil {
 			return err
 		}
-		if logLevelVal != nil {
-			logLevel = logLevelVal.(int)
+		if lvl, ok := logLevelVal.(int); ok {
+			logLevel = lvl
 		}
 		go func() {
 			for event := range prod.logs {
@@ -134,48 +157,6 @@ func (prod *producer) logging() error {
 	return nil
 }
 
-func (prod *producer) topicCatch() error {
-	val, err := prod.cfg.Get("topic", nil)
-	if err != nil {
-		return err
-	}
-	if val != nil {
-		defer delete(*prod.cfg, "topic")
-		topic, ok := val.(string)
-		if !ok {
-			return errors.New("incorrect value for topic key, must be string")
-		}
-		prod.topic = &topic
-		if prod.logger != nil {
-			prod.logger.Debug("topic has been setted for producer",
-				"topic", prod.topic)
-		}
-	}
-	return nil
-}
-
-func (prod *producer) flushTomeoutCatch() error {
-	if !prod.sync {
-		val, err := prod.cfg.Get("flush.timeout.ms", nil)
-		if err != nil {
-			return err
-		}
-		if val != nil {
-			fTo, ok := val.(int)
-			if !ok {
-				return errors.New("incorrect value for flush.timeout.ms key, must be int")
-			}
-			prod.fushTimeout = fTo
-			if prod.logger != nil {
-				prod.logger.Debug("timeout for flushing has been setted for producer",
-					"timeout", prod.fushTimeout)
-			}
-		}
-	}
-	delete(*prod.cfg, "flush.timeout.ms")
-	return nil
-}
-
 func (prod *producer) fetchMeta() error {
 	meta, err := prod.kprod.GetMetadata(nil, true, 500)
 	if err != nil {
@@ -223,17 +204,76 @@ func (prod *producer) syncRunning(ctx context.Context) error {
 			if prod.isStopped() {
 				continue
 			}
-			if err := prod.kprod.Produce(ctxMsg.msg, delivery); err != nil {
+			err, fatal := prod.deliverSync(ctxMsg, delivery)
+			if fatal {
 				return err
 			}
+			prod.errChan <- err
+		}
+	}
+}
+
+// deliverSync produces message and waits for delivery, retrying on delivery errors
+// according to retry config. Undelivered message is sent to DLQ topic if it set.
+// Message of NoRetry client is neither retried nor sent to DLQ.
+// fatal is true on producing errors, that should stop the producer.
+func (prod *producer) deliverSync(ctxMsg contextedMsg, delivery chan kafka.Event) (err error, fatal bool) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
 			select {
 			case <-ctxMsg.ctx.Done():
-				prod.errChan <- ctxMsg.ctx.Err()
-				prod.handleEventOnCtxDone(<-delivery)
-			case event := <-delivery:
-				prod.errChan <- event.(*kafka.Message).TopicPartition.Error
+				return ctxMsg.ctx.Err(), false
+			case <-time.After(prod.kcfg.Retry.Delay(attempt)):
 			}
 		}
+		if err := prod.kprod.Produce(ctxMsg.msg, delivery); err != nil {
+			return err, true
+		}
+		select {
+		case <-ctxMsg.ctx.Done():
+			prod.handleEventOnCtxDone(<-delivery)
+			return ctxMsg.ctx.Err(), false
+		case event := <-delivery:
+			err = event.(*kafka.Message).TopicPartition.Error
+		}
+		if err == nil || ctxMsg.noRetry || attempt >= prod.kcfg.Retry.Attempts {
+			break
+		}
+		if prod.logger != nil {
+			prod.logger.Debug("retrying message delivery",
+				"attempt", attempt+1,
+				"key", string(ctxMsg.msg.Key),
+				"error", err)
+		}
+	}
+	if err != nil && !ctxMsg.noRetry {
+		prod.toDLQ(ctxMsg.msg, delivery)
+	}
+	return err, false
+}
+
+// toDLQ sends undelivered message to DLQ topic, if delivery is nil the result
+// is handled by events loop of async producer.
+func (prod *producer) toDLQ(msg *kafka.Message, delivery chan kafka.Event) {
+	dlq := prod.kcfg.DLQ.Topic
+	if dlq == "" || (msg.TopicPartition.Topic != nil && *msg.TopicPartition.Topic == dlq) {
+		return
+	}
+	dlqMsg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &dlq, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        msg.Headers,
+	}
+	err := prod.kprod.Produce(dlqMsg, delivery)
+	if err == nil && delivery != nil {
+		err = (<-delivery).(*kafka.Message).TopicPartition.Error
+	}
+	if err != nil && prod.logger != nil {
+		prod.logger.Error("failed to send message to DLQ",
+			"topic", dlq,
+			"key", string(msg.Key),
+			"error", err)
 	}
 }
 
@@ -271,6 +311,7 @@ func (prod *producer) asyncRunning(ctx context.Context) error {
 						"key", string(msg.Key),
 						"error", msg.TopicPartition.Error)
 				}
+				prod.toDLQ(msg, nil)
 			} else {
 				if prod.logger != nil {
 					prod.logger.Debug("successfully produced record",
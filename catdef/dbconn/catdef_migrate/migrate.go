@@ -0,0 +1,312 @@
+package catdefmigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/surkovvs/gocat/catapp/appctx"
+	"github.com/surkovvs/gocat/catdef/dbconn/catdef_pgxp"
+	"github.com/surkovvs/gocat/catlog"
+
+	pgx5 "github.com/jackc/pgx/v5"
+)
+
+const defaultTable = "schema_migrations"
+
+var (
+	ErrDatabaseAhead   = errors.New("database schema is ahead of migrations")
+	ErrNoDownMigration = errors.New("down migration is not found")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_([^.]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // empty if there is no down file
+}
+
+// Step is migration applied in direction, up or down.
+type Step struct {
+	Migration
+	Down bool
+}
+
+func (s Step) String() string {
+	dir := "up"
+	if s.Down {
+		dir = "down"
+	}
+	return fmt.Sprintf("%04d_%s.%s", s.Version, s.Name, dir)
+}
+
+// Migrator is initializer which applies migrations read from NNNN_name.up.sql and
+// NNNN_name.down.sql files, register it right after the pool and before its users.
+// Applied versions are stored in table, migrations of concurrent replicas are
+// serialized with advisory lock. Start fails if database has version unknown
+// to migrations, as it is ahead of the binary.
+type Migrator struct {
+	pool       *catdefpgxp.Pool
+	fsys       fs.FS
+	table      string
+	target     *int64
+	dryRun     bool
+	logger     catlog.Logger
+	migrations []Migration
+}
+
+type migratorOption func(*Migrator)
+
+// WithTable sets table of applied versions, could be qualified with schema.
+func WithTable(table string) migratorOption {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithTarget sets version to migrate to, migrations above it are rolled back,
+// by default the latest version is the target.
+func WithTarget(version int64) migratorOption {
+	return func(m *Migrator) {
+		m.target = &version
+	}
+}
+
+// WithDryRun only logs steps to be applied.
+func WithDryRun() migratorOption {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+// WithLogger sets logger, by default logger of module context is used.
+func WithLogger(logger catlog.Logger) migratorOption {
+	return func(m *Migrator) {
+		m.logger = logger
+	}
+}
+
+// New reads migrations from root of fsys, use fs.Sub for embedded directory.
+func New(pool *catdefpgxp.Pool, fsys fs.FS, opts ...migratorOption) (*Migrator, error) {
+	m := &Migrator{
+		pool:  pool,
+		fsys:  fsys,
+		table: defaultTable,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	migrations, err := readMigrations(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+	m.migrations = migrations
+	return m, nil
+}
+
+func readMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf("version %d has different names: %s, %s", version, mig.Name, match[2])
+		}
+		if match[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("version %d has no up migration", mig.Version)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func (m *Migrator) Init(ctx context.Context) error {
+	return m.Migrate(ctx)
+}
+
+// Migrate applies steps to the target version, each step in own transaction.
+func (m *Migrator) Migrate(ctx context.Context) error {
+	logger := m.logger
+	if logger == nil {
+		logger = appctx.Logger(ctx)
+	}
+
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire conn: %w", err)
+	}
+	defer conn.Release()
+
+	// session lock, held on the same connection until unlocked
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock(hashtext($1))", m.table); err != nil {
+		return fmt.Errorf("advisory lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock(hashtext($1))", m.table); err != nil {
+			logger.Warn("migrations: advisory unlock failed", "error", err)
+		}
+	}()
+
+	applied, err := m.applied(ctx, conn.Conn())
+	if err != nil {
+		return err
+	}
+	steps, err := m.plan(applied)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		logger.Debug("migrations: database is up to date")
+		return nil
+	}
+
+	for _, step := range steps {
+		if m.dryRun {
+			logger.Info("migrations: dry run, step is not applied", "step", step.String())
+			continue
+		}
+		if err := pgx5.BeginFunc(ctx, conn, func(tx pgx5.Tx) error {
+			return m.apply(ctx, tx, step)
+		}); err != nil {
+			return fmt.Errorf("migration %s: %w", step, err)
+		}
+		logger.Info("migrations: step has been applied", "step", step.String())
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, tx pgx5.Tx, step Step) error {
+	table := m.tableIdent()
+	if step.Down {
+		if _, err := tx.Exec(ctx, step.Migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM "+table+" WHERE version = $1", step.Version)
+		return err
+	}
+	if _, err := tx.Exec(ctx, step.Up); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO "+table+" (version, name) VALUES ($1, $2)", step.Version, step.Name)
+	return err
+}
+
+// applied returns applied versions, table is created if it does not exist
+func (m *Migrator) applied(ctx context.Context, conn *pgx5.Conn) (map[int64]struct{}, error) {
+	if m.dryRun {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", m.table).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("check table: %w", err)
+		}
+		if !exists {
+			return map[int64]struct{}{}, nil
+		}
+	} else if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+m.tableIdent()+` (
+	version    bigint PRIMARY KEY,
+	name       text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`); err != nil {
+		return nil, fmt.Errorf("create table: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version FROM "+m.tableIdent())
+	if err != nil {
+		return nil, fmt.Errorf("select versions: %w", err)
+	}
+	versions, err := pgx5.CollectRows(rows, pgx5.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("select versions: %w", err)
+	}
+	applied := make(map[int64]struct{}, len(versions))
+	for _, version := range versions {
+		applied[version] = struct{}{}
+	}
+	return applied, nil
+}
+
+func (m *Migrator) plan(applied map[int64]struct{}) ([]Step, error) {
+	known := make(map[int64]struct{}, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = struct{}{}
+	}
+	var unknown []int64
+	for version := range applied {
+		if _, ok := known[version]; !ok {
+			unknown = append(unknown, version)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+		return nil, fmt.Errorf("%w: unknown applied versions %v", ErrDatabaseAhead, unknown)
+	}
+
+	var target int64 = -1
+	if len(m.migrations) > 0 {
+		target = m.migrations[len(m.migrations)-1].Version
+	}
+	if m.target != nil {
+		target = *m.target
+	}
+
+	var steps []Step
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok && mig.Version <= target {
+			steps = append(steps, Step{Migration: mig})
+		}
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok || mig.Version <= target {
+			continue
+		}
+		if mig.Down == "" {
+			return nil, fmt.Errorf("%w: version %d", ErrNoDownMigration, mig.Version)
+		}
+		steps = append(steps, Step{Migration: mig, Down: true})
+	}
+	return steps, nil
+}
+
+func (m *Migrator) tableIdent() string {
+	return pgx5.Identifier(strings.Split(m.table, ".")).Sanitize()
+}
@@ -0,0 +1,64 @@
+package catdefmigrate
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPlan(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_init.up.sql":     {Data: []byte("CREATE TABLE a ()")},
+		"0001_init.down.sql":   {Data: []byte("DROP TABLE a")},
+		"0002_users.up.sql":    {Data: []byte("CREATE TABLE b ()")},
+		"0003_orders.up.sql":   {Data: []byte("CREATE TABLE c ()")},
+		"0003_orders.down.sql": {Data: []byte("DROP TABLE c")},
+		"README.md":            {Data: []byte("ignored")},
+	}
+	m, err := New(nil, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planned := func(applied ...int64) []string {
+		t.Helper()
+		set := map[int64]struct{}{}
+		for _, v := range applied {
+			set[v] = struct{}{}
+		}
+		steps, err := m.plan(set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res []string
+		for _, step := range steps {
+			res = append(res, step.String())
+		}
+		return res
+	}
+
+	if got := planned(1); !slices.Equal(got, []string{"0002_users.up", "0003_orders.up"}) {
+		t.Fatalf("up: got %v", got)
+	}
+	if got := planned(1, 2, 3); len(got) != 0 {
+		t.Fatalf("up to date: got %v", got)
+	}
+	m.target = new(int64)
+	*m.target = 2
+	if got := planned(1, 2, 3); !slices.Equal(got, []string{"0003_orders.down"}) {
+		t.Fatalf("down: got %v", got)
+	}
+	*m.target = 1
+	if _, err := m.plan(map[int64]struct{}{1: {}, 2: {}}); !errors.Is(err, ErrNoDownMigration) {
+		t.Fatalf("no down error expected, got %v", err)
+	}
+	if _, err := m.plan(map[int64]struct{}{1: {}, 4: {}}); !errors.Is(err, ErrDatabaseAhead) {
+		t.Fatalf("ahead error expected, got %v", err)
+	}
+
+	fsys["0002_other.down.sql"] = &fstest.MapFile{Data: []byte("")}
+	if _, err := New(nil, fsys); err == nil {
+		t.Fatal("error expected for different names of version")
+	}
+}
@@ -0,0 +1,449 @@
+package catdefoutbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/surkovvs/gocat/catapp/appctx"
+	"github.com/surkovvs/gocat/catdef/brokers/catkafkaprod"
+	"github.com/surkovvs/gocat/catdef/dbconn/catdef_pgxp"
+	"github.com/surkovvs/gocat/catlog"
+
+	pgx5 "github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultTable        = "outbox"
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	defaultMaxAttempts  = 10
+	defaultBackoff      = time.Second
+	defaultMaxBackoff   = 5 * time.Minute
+)
+
+var (
+	ErrAsyncProducer  = errors.New("outbox relay requires sync producer to confirm delivery")
+	ErrNoPartitioner  = errors.New("outbox relay requires producer partitioner to keep order of keys")
+	ErrAlreadyRunning = errors.New("outbox relay is already running")
+)
+
+// Message is outbox row being published.
+type Message struct {
+	ID       int64
+	Topic    string
+	Key      string
+	Payload  []byte
+	Attempts int // failed attempts before the current one
+}
+
+// Relay is runner, which publishes rows of outbox table written in the same
+// transactions as business data. Table is expected to have schema:
+//
+//	CREATE TABLE outbox (
+//		id              bigserial PRIMARY KEY,
+//		topic           text NOT NULL,
+//		key             text NOT NULL DEFAULT '',
+//		payload         bytea NOT NULL,
+//		attempts        int NOT NULL DEFAULT 0,
+//		next_attempt_at timestamptz NOT NULL DEFAULT now(),
+//		last_error      text,
+//		sent_at         timestamptz,
+//		failed_at       timestamptz
+//	);
+//	CREATE INDEX ON outbox (id) WHERE sent_at IS NULL AND failed_at IS NULL;
+//
+// Batches are claimed with FOR UPDATE SKIP LOCKED, so relays of several replicas
+// share the table. Rows with the same non-empty key are published in id order,
+// key is locked with advisory lock and row is not published while earlier row of
+// its key is pending, producer must partition by key to keep the order in Kafka.
+// Row is marked sent only after delivery is confirmed, in the claim transaction,
+// so rows of a batch interrupted by crash are published again.
+// Failed row is retried with backoff, after max attempts it is marked failed,
+// stops blocking its key and is sent to DLQ topic of the producer if it is set.
+// Retries and DLQ of the producer itself are not used for rows.
+type Relay struct {
+	pool         *catdefpgxp.Pool
+	publish      func(ctx context.Context, msg Message) error
+	toDLQ        func(ctx context.Context, msg Message) error // nil if DLQ is not set
+	table        string
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	onPoison     func(ctx context.Context, msg Message, err error)
+	logger       catlog.Logger
+
+	mu    *sync.Mutex
+	stop  chan struct{}
+	abort context.CancelFunc
+	done  chan struct{}
+}
+
+type relayOption func(*Relay)
+
+// WithTable sets outbox table, could be qualified with schema.
+func WithTable(table string) relayOption {
+	return func(r *Relay) {
+		r.table = table
+	}
+}
+
+// WithBatchSize sets max number of rows claimed in one transaction, 100 by default.
+func WithBatchSize(size int) relayOption {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+// WithPollInterval sets delay of the next claim after batch,
+// which has not been full, 1s by default.
+func WithPollInterval(interval time.Duration) relayOption {
+	return func(r *Relay) {
+		r.pollInterval = interval
+	}
+}
+
+// WithRetry sets attempts to publish row before it is marked failed and delay
+// before the first retry, doubled on each next one up to maxBackoff, which
+// is not limited if it is not positive.
+// By default 10 attempts are made with backoff from 1s up to 5m.
+func WithRetry(maxAttempts int, backoff, maxBackoff time.Duration) relayOption {
+	return func(r *Relay) {
+		r.maxAttempts = maxAttempts
+		r.backoff = backoff
+		r.maxBackoff = maxBackoff
+	}
+}
+
+// WithPoisonHandler sets func called when row is marked failed.
+func WithPoisonHandler(onPoison func(ctx context.Context, msg Message, err error)) relayOption {
+	return func(r *Relay) {
+		r.onPoison = onPoison
+	}
+}
+
+// WithLogger sets logger, by default logger of module context is used.
+func WithLogger(logger catlog.Logger) relayOption {
+	return func(r *Relay) {
+		r.logger = logger
+	}
+}
+
+// New creates relay publishing to topics of rows with client,
+// which must be sync and have partitioner, see catkafkaprod.New.
+func New(pool *catdefpgxp.Pool, client catkafkaprod.ProdClient, opts ...relayOption) (*Relay, error) {
+	if !client.IsSync() {
+		return nil, ErrAsyncProducer
+	}
+	if !client.HasPartitioner() {
+		return nil, ErrNoPartitioner
+	}
+	client = client.NoRetry()
+	r := &Relay{
+		pool: pool,
+		publish: func(ctx context.Context, msg Message) error {
+			return client.Topic(msg.Topic).Produce(ctx, []byte(msg.Key), msg.Payload)
+		},
+		table:        defaultTable,
+		batchSize:    defaultBatchSize,
+		pollInterval: defaultPollInterval,
+		maxAttempts:  defaultMaxAttempts,
+		backoff:      defaultBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		mu:           &sync.Mutex{},
+		stop:         make(chan struct{}),
+	}
+	if dlq := client.DLQTopic(); dlq != "" {
+		r.toDLQ = func(ctx context.Context, msg Message) error {
+			return client.Topic(dlq).Produce(ctx, []byte(msg.Key), msg.Payload)
+		}
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Run publishes batches until ctx is done or Shutdown is called,
+// batch in progress is completed in both cases.
+func (r *Relay) Run(ctx context.Context) error {
+	if r.pool.Pool == nil {
+		return catdefpgxp.ErrNotInitialized
+	}
+	logger := r.logger
+	if logger == nil {
+		logger = appctx.Logger(ctx)
+	}
+
+	r.mu.Lock()
+	if r.done != nil {
+		r.mu.Unlock()
+		return ErrAlreadyRunning
+	}
+	// batch is not interrupted by ctx, only by Shutdown deadline
+	batchCtx, abort := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	r.abort, r.done = abort, done
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.abort, r.done = nil, nil
+		r.mu.Unlock()
+		close(done)
+	}()
+	defer abort()
+
+	var delay time.Duration
+	for {
+		select {
+		case <-r.stop:
+			return nil
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		processed, err := r.relayBatch(batchCtx, logger)
+		if err != nil {
+			logger.Error("outbox relay: batch failed", "error", err)
+		}
+		delay = r.pollInterval
+		if err == nil && processed == r.batchSize {
+			delay = 0
+		}
+	}
+}
+
+// relayBatch claims and publishes batch in transaction, returns number of rows
+// published or failed, rows of keys locked by other relays are not counted
+func (r *Relay) relayBatch(ctx context.Context, logger catlog.Logger) (int, error) {
+	var processed int
+	err := pgx5.BeginFunc(ctx, r.pool, func(tx pgx5.Tx) error {
+		msgs, err := r.claim(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+		locked, pending, err := r.lockKeys(ctx, tx, msgs)
+		if err != nil {
+			return err
+		}
+
+		blocked := make(map[string]struct{})
+		for _, msg := range ready(msgs, locked, pending) {
+			if _, ok := blocked[msg.Key]; ok {
+				continue
+			}
+			if err := r.publish(ctx, msg); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err() // aborted, not an attempt
+				}
+				if msg.Key != "" {
+					blocked[msg.Key] = struct{}{}
+				}
+				if err := r.markFailed(ctx, tx, msg, err, logger); err != nil {
+					return err
+				}
+				processed++
+				continue
+			}
+			if _, err := tx.Exec(ctx, "UPDATE "+r.tableIdent()+" SET sent_at = now() WHERE id = $1", msg.ID); err != nil {
+				return fmt.Errorf("mark sent %d: %w", msg.ID, err)
+			}
+			processed++
+		}
+		return nil
+	})
+	return processed, err
+}
+
+func (r *Relay) claim(ctx context.Context, tx pgx5.Tx) ([]Message, error) {
+	rows, err := tx.Query(ctx, `SELECT id, topic, key, payload, attempts FROM `+r.tableIdent()+`
+WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= now()
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED`, r.batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("claim: %w", err)
+	}
+	msgs, err := pgx5.CollectRows(rows, pgx5.RowToStructByPos[Message])
+	if err != nil {
+		return nil, fmt.Errorf("claim: %w", err)
+	}
+	return msgs, nil
+}
+
+// lockKeys takes advisory locks of keys till the end of transaction and returns
+// locked keys with their pending ids up to the last claimed one in id order
+func (r *Relay) lockKeys(ctx context.Context, tx pgx5.Tx, msgs []Message) (map[string]bool, map[string][]int64, error) {
+	var keys []string
+	seen := make(map[string]struct{})
+	for _, msg := range msgs {
+		if _, ok := seen[msg.Key]; !ok && msg.Key != "" {
+			seen[msg.Key] = struct{}{}
+			keys = append(keys, msg.Key)
+		}
+	}
+	locked := make(map[string]bool, len(keys))
+	pending := make(map[string][]int64, len(keys))
+	if len(keys) == 0 {
+		return locked, pending, nil
+	}
+
+	rows, err := tx.Query(ctx, `SELECT k, pg_try_advisory_xact_lock(hashtext($1), hashtext(k))
+FROM unnest($2::text[]) AS k`, r.table, keys)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lock keys: %w", err)
+	}
+	var lockedKeys []string
+	var key string
+	var ok bool
+	if _, err := pgx5.ForEachRow(rows, []any{&key, &ok}, func() error {
+		locked[key] = ok
+		if ok {
+			lockedKeys = append(lockedKeys, key)
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("lock keys: %w", err)
+	}
+	if len(lockedKeys) == 0 {
+		return locked, pending, nil
+	}
+
+	rows, err = tx.Query(ctx, `SELECT key, id FROM `+r.tableIdent()+`
+WHERE key = ANY($1) AND id <= $2 AND sent_at IS NULL AND failed_at IS NULL
+ORDER BY id`, lockedKeys, msgs[len(msgs)-1].ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pending ids: %w", err)
+	}
+	var id int64
+	if _, err := pgx5.ForEachRow(rows, []any{&key, &id}, func() error {
+		pending[key] = append(pending[key], id)
+		return nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("pending ids: %w", err)
+	}
+	return locked, pending, nil
+}
+
+// ready returns messages which could be published keeping per-key order:
+// key is locked by this relay and claimed rows of key match its pending ids
+// from the earliest one, key stops at the first pending row, which is not
+// claimed, e.g. waits for retry or is claimed by other relay
+func ready(msgs []Message, locked map[string]bool, pending map[string][]int64) []Message {
+	res := make([]Message, 0, len(msgs))
+	next := make(map[string]int)     // index of the next pending id of key
+	stopped := make(map[string]bool) // key has gap
+	for _, msg := range msgs {
+		if msg.Key == "" {
+			res = append(res, msg)
+			continue
+		}
+		if !locked[msg.Key] || stopped[msg.Key] {
+			continue
+		}
+		ids, i := pending[msg.Key], next[msg.Key]
+		if i >= len(ids) || ids[i] != msg.ID {
+			stopped[msg.Key] = true
+			continue
+		}
+		next[msg.Key] = i + 1
+		res = append(res, msg)
+	}
+	return res
+}
+
+func (r *Relay) markFailed(ctx context.Context, tx pgx5.Tx, msg Message, pubErr error, logger catlog.Logger) error {
+	attempts := msg.Attempts + 1
+	if attempts >= r.maxAttempts {
+		if _, err := tx.Exec(ctx, "UPDATE "+r.tableIdent()+` SET attempts = $2, last_error = $3, failed_at = now()
+WHERE id = $1`, msg.ID, attempts, pubErr.Error()); err != nil {
+			return fmt.Errorf("mark failed %d: %w", msg.ID, err)
+		}
+		logger.Error("outbox relay: row is poisoned, attempts are exceeded",
+			"id", msg.ID,
+			"topic", msg.Topic,
+			"key", msg.Key,
+			"attempts", attempts,
+			"error", pubErr)
+		if r.toDLQ != nil {
+			if err := r.toDLQ(ctx, msg); err != nil {
+				logger.Error("outbox relay: failed to send poisoned row to DLQ",
+					"id", msg.ID,
+					"key", msg.Key,
+					"error", err)
+			}
+		}
+		if r.onPoison != nil {
+			r.onPoison(ctx, msg, pubErr)
+		}
+		return nil
+	}
+
+	delay := r.retryDelay(attempts)
+	if _, err := tx.Exec(ctx, "UPDATE "+r.tableIdent()+` SET attempts = $2, last_error = $3,
+next_attempt_at = now() + make_interval(secs => $4) WHERE id = $1`,
+		msg.ID, attempts, pubErr.Error(), delay.Seconds()); err != nil {
+		return fmt.Errorf("mark retry %d: %w", msg.ID, err)
+	}
+	logger.Warn("outbox relay: publish failed, row is retried",
+		"id", msg.ID,
+		"topic", msg.Topic,
+		"key", msg.Key,
+		"attempt", attempts,
+		"delay", delay.String(),
+		"error", pubErr)
+	return nil
+}
+
+// retryDelay returns delay after failed attempt, which is counted from 1
+func (r *Relay) retryDelay(attempt int) time.Duration {
+	delay := r.backoff
+	for i := 1; i < attempt && delay < math.MaxInt64/2; i++ {
+		if r.maxBackoff > 0 && delay >= r.maxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if r.maxBackoff > 0 && delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+	return delay
+}
+
+func (r *Relay) tableIdent() string {
+	return pgx5.Identifier(strings.Split(r.table, ".")).Sanitize()
+}
+
+// Shutdown stops claiming and waits for batch in progress, if ctx is done
+// first, batch is aborted and its rows are published again later.
+func (r *Relay) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	abort, done := r.abort, r.done
+	r.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		abort()
+		<-done
+		return fmt.Errorf("drain: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,128 @@
+package catdefoutbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catdb"
+	"github.com/surkovvs/gocat/catdef/brokers/catkafkaprod"
+	"github.com/surkovvs/gocat/catdef/dbconn/catdef_pgxp"
+	"github.com/surkovvs/gocat/catkafka"
+)
+
+func TestNewChecksProducer(t *testing.T) {
+	cfg := &catcfg.Config{Kafka: map[string]catkafka.ConfigKafka{
+		"async": {},
+		"sync":  {Sync: true},
+		"hash":  {Sync: true, PartitionHash: catkafka.PartitionHashCRC32},
+	}}
+	for tag, expected := range map[string]error{
+		"async": ErrAsyncProducer,
+		"sync":  ErrNoPartitioner,
+		"hash":  nil,
+	} {
+		client := catkafkaprod.New(cfg, tag, false).NewClient()
+		if _, err := New(nil, client); !errors.Is(err, expected) {
+			t.Errorf("%s: expected %v, got %v", tag, expected, err)
+		}
+	}
+}
+
+func TestReady(t *testing.T) {
+	msgs := []Message{
+		{ID: 3, Key: "a"},
+		{ID: 4, Key: "b"},
+		{ID: 5},
+		{ID: 6, Key: "a"},
+		{ID: 7, Key: "c"},
+		{ID: 8, Key: "b"},
+	}
+	locked := map[string]bool{"a": true, "b": true, "c": false}
+	pending := map[string][]int64{
+		"a": {3, 6},
+		"b": {2, 4, 8}, // earlier row is pending, e.g. waits for retry
+	}
+
+	var ids []int64
+	for _, msg := range ready(msgs, locked, pending) {
+		ids = append(ids, msg.ID)
+	}
+	if expected := []int64{3, 5, 6}; !slices.Equal(ids, expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+}
+
+func TestReadyStopsAtGap(t *testing.T) {
+	msgs := []Message{
+		{ID: 1, Key: "a"},
+		{ID: 3, Key: "a"},
+		{ID: 4, Key: "a"},
+	}
+	locked := map[string]bool{"a": true}
+	// row 2 is pending, but not claimed, e.g. claimed by other relay
+	pending := map[string][]int64{"a": {1, 2, 3, 4}}
+
+	var ids []int64
+	for _, msg := range ready(msgs, locked, pending) {
+		ids = append(ids, msg.ID)
+	}
+	if expected := []int64{1}; !slices.Equal(ids, expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	r := &Relay{backoff: time.Second, maxBackoff: 5 * time.Second}
+	for attempt, expected := range map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		3:  4 * time.Second,
+		4:  5 * time.Second,
+		60: 5 * time.Second,
+	} {
+		if delay := r.retryDelay(attempt); delay != expected {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, expected, delay)
+		}
+	}
+
+	r = &Relay{backoff: time.Second} // no max backoff
+	if delay := r.retryDelay(4); delay != 8*time.Second {
+		t.Errorf("expected 8s without max backoff, got %v", delay)
+	}
+	if delay := r.retryDelay(1000); delay <= 0 {
+		t.Errorf("expected positive delay without max backoff, got %v", delay)
+	}
+}
+
+func TestRunAgain(t *testing.T) {
+	cfg := catcfg.Config{}
+	cfg.ConfigDB = catdb.ConfigDB{Host: "127.0.0.1", Port: 1, Name: "db", User: "user"}
+	pool, err := catdefpgxp.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relay := &Relay{pool: pool, mu: &sync.Mutex{}, stop: make(chan struct{}), pollInterval: time.Millisecond}
+	if err := relay.Run(context.Background()); !errors.Is(err, catdefpgxp.ErrNotInitialized) {
+		t.Fatalf("ErrNotInitialized expected, got %v", err)
+	}
+
+	if err := pool.Init(context.Background()); err != nil { // connects lazily
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := relay.Run(ctx); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+	if err := relay.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
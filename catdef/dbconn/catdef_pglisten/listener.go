@@ -0,0 +1,254 @@
+package catdefpglisten
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/surkovvs/gocat/catapp/appctx"
+	"github.com/surkovvs/gocat/catdef/dbconn/catdef_pgxp"
+	"github.com/surkovvs/gocat/catlog"
+
+	pgx5 "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+	unlistenTimeout   = 5 * time.Second
+)
+
+var (
+	ErrNoChannels     = errors.New("no channels to listen")
+	ErrAlreadyRunning = errors.New("listener is already running")
+)
+
+type Notification struct {
+	Channel string
+	Payload string
+	PID     uint32 // backend process which sent notification
+}
+
+// Handler processes notification, returned error is logged,
+// listening goes on.
+type Handler func(ctx context.Context, n Notification) error
+
+// Listener is runner, which holds dedicated connection of the pool and
+// LISTENs on channels of registered handlers. After connection loss it
+// reconnects with backoff and LISTENs again, notifications sent meanwhile
+// are lost, so gap handler is called to let consumers resync.
+type Listener struct {
+	pool       *catdefpgxp.Pool
+	handlers   map[string][]Handler
+	onGap      func(ctx context.Context, channels []string)
+	backoff    time.Duration
+	maxBackoff time.Duration
+	logger     catlog.Logger
+
+	mu     *sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type listenerOption func(*Listener)
+
+// WithGapHandler sets func called after reconnection, when notifications
+// of channels could have been missed.
+func WithGapHandler(onGap func(ctx context.Context, channels []string)) listenerOption {
+	return func(l *Listener) {
+		l.onGap = onGap
+	}
+}
+
+// WithBackoff sets delay before the first reconnection attempt, doubled on each
+// next one up to maxBackoff, which is not limited if it is not positive,
+// 100ms and 30s by default.
+func WithBackoff(initial, maxBackoff time.Duration) listenerOption {
+	return func(l *Listener) {
+		l.backoff = initial
+		l.maxBackoff = maxBackoff
+	}
+}
+
+// WithLogger sets logger, by default logger of module context is used.
+func WithLogger(logger catlog.Logger) listenerOption {
+	return func(l *Listener) {
+		l.logger = logger
+	}
+}
+
+func New(pool *catdefpgxp.Pool, opts ...listenerOption) *Listener {
+	l := &Listener{
+		pool:       pool,
+		handlers:   make(map[string][]Handler),
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+		mu:         &sync.Mutex{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Handle registers handler of channel, must be called before Run.
+// Channel name is case sensitive, as it is quoted in LISTEN.
+func (l *Listener) Handle(channel string, h Handler) {
+	l.handlers[channel] = append(l.handlers[channel], h)
+}
+
+// HandleJSON registers handler of channel with payload decoded from JSON.
+func HandleJSON[T any](l *Listener, channel string, fn func(ctx context.Context, payload T) error) {
+	l.Handle(channel, func(ctx context.Context, n Notification) error {
+		var payload T
+		if err := json.Unmarshal([]byte(n.Payload), &payload); err != nil {
+			return fmt.Errorf("decode payload: %w", err)
+		}
+		return fn(ctx, payload)
+	})
+}
+
+func (l *Listener) channels() []string {
+	channels := make([]string, 0, len(l.handlers))
+	for channel := range l.handlers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// Run listens until ctx is done or Shutdown is called.
+func (l *Listener) Run(ctx context.Context) error {
+	if len(l.handlers) == 0 {
+		return ErrNoChannels
+	}
+	if l.pool == nil || l.pool.Pool == nil {
+		return catdefpgxp.ErrNotInitialized
+	}
+	logger := l.logger
+	if logger == nil {
+		logger = appctx.Logger(ctx)
+	}
+
+	l.mu.Lock()
+	if l.done != nil {
+		l.mu.Unlock()
+		return ErrAlreadyRunning
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	l.cancel, l.done = cancel, done
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.cancel, l.done = nil, nil
+		l.mu.Unlock()
+		close(done)
+	}()
+	defer cancel()
+
+	channels := l.channels()
+	backoff := l.backoff
+	connected := false
+	for {
+		err := l.listen(ctx, logger, channels, connected, func() {
+			connected = true
+			backoff = l.backoff
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		logger.Warn("pg listener: connection lost, reconnecting",
+			"error", err,
+			"backoff", backoff.String())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = l.nextBackoff(backoff)
+	}
+}
+
+func (l *Listener) nextBackoff(backoff time.Duration) time.Duration {
+	if backoff < math.MaxInt64/2 {
+		backoff *= 2
+	}
+	if l.maxBackoff > 0 && backoff > l.maxBackoff {
+		backoff = l.maxBackoff
+	}
+	return backoff
+}
+
+// listen acquires connection, LISTENs and dispatches notifications until
+// connection fails, established is called once channels are listened.
+func (l *Listener) listen(ctx context.Context, logger catlog.Logger, channels []string, reconnect bool, established func()) error {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire conn: %w", err)
+	}
+	defer release(conn, logger)
+
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx5.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("listen %s: %w", channel, err)
+		}
+	}
+	established()
+	logger.Debug("pg listener: listening", "channels", channels)
+	if reconnect && l.onGap != nil {
+		l.onGap(ctx, channels)
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		notification := Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID}
+		for _, h := range l.handlers[n.Channel] {
+			if err := h(ctx, notification); err != nil {
+				logger.Error("pg listener: handler failed",
+					"channel", n.Channel,
+					"error", err)
+			}
+		}
+	}
+}
+
+// release returns connection to the pool without subscriptions,
+// broken connection is destroyed by the pool.
+func release(conn *pgxpool.Conn, logger catlog.Logger) {
+	if !conn.Conn().IsClosed() {
+		ctx, cancel := context.WithTimeout(context.Background(), unlistenTimeout)
+		defer cancel()
+		if _, err := conn.Exec(ctx, "UNLISTEN *"); err != nil {
+			logger.Warn("pg listener: unlisten failed", "error", err)
+		}
+	}
+	conn.Release()
+}
+
+// Shutdown stops Run and waits until connection is released.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	cancel, done := l.cancel, l.done
+	l.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
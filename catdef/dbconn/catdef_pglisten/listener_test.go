@@ -0,0 +1,95 @@
+package catdefpglisten
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catdb"
+	"github.com/surkovvs/gocat/catdef/dbconn/catdef_pgxp"
+)
+
+func TestHandleJSON(t *testing.T) {
+	type invalidation struct {
+		Key string `json:"key"`
+	}
+	l := New(nil)
+	var keys []string
+	HandleJSON(l, "cache", func(_ context.Context, payload invalidation) error {
+		keys = append(keys, payload.Key)
+		return nil
+	})
+	l.Handle("users", func(context.Context, Notification) error { return nil })
+
+	if channels := l.channels(); !slices.Equal(channels, []string{"cache", "users"}) {
+		t.Fatalf("unexpected channels %v", channels)
+	}
+	h := l.handlers["cache"][0]
+	if err := h(context.Background(), Notification{Channel: "cache", Payload: `{"key":"user:1"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h(context.Background(), Notification{Channel: "cache", Payload: `user:2`}); err == nil {
+		t.Fatal("decode error expected")
+	}
+	if !slices.Equal(keys, []string{"user:1"}) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
+
+func TestRunWithoutHandlers(t *testing.T) {
+	l := New(nil)
+	if err := l.Run(context.Background()); !errors.Is(err, ErrNoChannels) {
+		t.Fatalf("ErrNoChannels expected, got %v", err)
+	}
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunAgain(t *testing.T) {
+	cfg := catcfg.Config{}
+	cfg.ConfigDB = catdb.ConfigDB{Host: "127.0.0.1", Port: 1, Name: "db", User: "user"}
+	pool, err := catdefpgxp.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New(pool)
+	l.Handle("users", func(context.Context, Notification) error { return nil })
+	if err := l.Run(context.Background()); !errors.Is(err, catdefpgxp.ErrNotInitialized) {
+		t.Fatalf("ErrNotInitialized expected, got %v", err)
+	}
+
+	if err := pool.Init(context.Background()); err != nil { // connects lazily
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := l.Run(ctx); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	l := New(nil, WithBackoff(time.Second, 0))
+	if backoff := l.nextBackoff(time.Second); backoff != 2*time.Second {
+		t.Fatalf("expected 2s without max backoff, got %v", backoff)
+	}
+	if backoff := l.nextBackoff(math.MaxInt64 - 1); backoff <= 0 {
+		t.Fatalf("expected positive backoff, got %v", backoff)
+	}
+
+	l = New(nil, WithBackoff(time.Second, 3*time.Second))
+	if backoff := l.nextBackoff(2 * time.Second); backoff != 3*time.Second {
+		t.Fatalf("expected max backoff, got %v", backoff)
+	}
+}
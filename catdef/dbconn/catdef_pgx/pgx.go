@@ -1,6 +1,14 @@
+// Package catdefpgx is pgx v3 connector.
+//
+// Deprecated: pgx v3 is not maintained, use pgx v5 connectors with the same config:
+// catdef_pgxp.New for pool (replaces InitPGXPool, *pgx.ConnPool methods are
+// available on *pgxpool.Pool with context as the first argument) and
+// catdef_pgxc.New for single connection. Both are gocat modules, register them
+// with AddModuleToGroup instead of creating the pool before app start.
 package catdefpgx
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/jackc/pgx"
@@ -8,17 +16,30 @@ import (
 	"github.com/surkovvs/gocat/catlog"
 )
 
-// TODO: implement methods
+// ErrTargetSessionAttrsUnsupported is returned for target session attrs
+// other than any and read-write, which pgx v3 does not support.
+var ErrTargetSessionAttrsUnsupported = errors.New("target session attrs are not supported by pgx v3 driver, use any or read-write")
+
+// InitPGXPool creates pool, which is connected immediately.
+//
+// Deprecated: use catdef_pgxp.New.
 func InitPGXPool(cfg catcfg.Config) (*pgx.ConnPool, error) {
-	dsn := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=disable",
-		cfg.Host, cfg.Port,
-		cfg.Name,
-		cfg.User,
-		cfg.Pass,
-	)
-	connCfg, err := pgx.ParseConnectionString(dsn)
+	switch attrs := cfg.TargetSessionAttrs; {
+	case cfg.DSN != "", attrs == "", attrs == "any", attrs == "read-write":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrTargetSessionAttrsUnsupported, attrs)
+	}
+	connCfg, err := pgx.ParseConnectionString(cfg.GetDSN())
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parse connection string: %w", err)
+	}
+	if cfg.ServerName != "" {
+		if connCfg.TLSConfig != nil {
+			connCfg.TLSConfig.ServerName = cfg.ServerName
+		}
+		if connCfg.FallbackTLSConfig != nil {
+			connCfg.FallbackTLSConfig.ServerName = cfg.ServerName
+		}
 	}
 
 	if cfg.Logger != nil {
@@ -26,9 +47,13 @@ func InitPGXPool(cfg catcfg.Config) (*pgx.ConnPool, error) {
 		connCfg.Logger = logAdapter{cfg.Logger}
 	}
 
-	pool, err := pgx.NewConnPool(pgx.ConnPoolConfig{
+	poolCfg := pgx.ConnPoolConfig{
 		ConnConfig: connCfg,
-	})
+	}
+	if cfg.MaxConns != nil {
+		poolCfg.MaxConnections = int(*cfg.MaxConns)
+	}
+	pool, err := pgx.NewConnPool(poolCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,110 @@
+// Package catdefpgxc is pgx v5 single connection connector for LISTEN/NOTIFY,
+// COPY and other workloads bound to one session.
+package catdefpgxc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catlog"
+	"github.com/surkovvs/gocat/internal/pgx5cfg"
+	"github.com/surkovvs/gocat/internal/pgx5trace"
+
+	pgx5 "github.com/jackc/pgx/v5"
+)
+
+var ErrConnClosed = errors.New("connection is closed")
+
+// Conn is not safe for concurrent use, as pgx5.Conn itself,
+// except Healthcheck, which could be called while owner reconnects.
+type Conn struct {
+	cfg        *pgx5.ConnConfig
+	logger     catlog.Logger
+	logConnect bool
+	mu         *sync.Mutex // guards Conn against Healthcheck
+	*pgx5.Conn
+}
+
+func New(cfg catcfg.Config) (*Conn, error) {
+	cfgConn, err := pgx5.ParseConfig(cfg.GetDSN())
+	if err != nil {
+		return nil, fmt.Errorf("conn parse config: %w", err)
+	}
+	pgx5cfg.SetServerName(cfgConn, cfg.ServerName)
+
+	if cfg.Logger != nil && cfg.TraceEnabled() {
+		cfgConn.Tracer = pgx5trace.New(cfg.Logger, cfg.ConfigTrace)
+	}
+
+	return &Conn{
+		cfg:        cfgConn,
+		logger:     cfg.Logger,
+		logConnect: cfg.Logger != nil && cfg.ConfigDB.ConfigPool.LogConnectOperations,
+		mu:         &sync.Mutex{},
+	}, nil
+}
+
+func (conn *Conn) Init(ctx context.Context) error {
+	return conn.Connect(ctx)
+}
+
+// Connect opens new connection, previous one is closed,
+// use it to restore connection after failure.
+func (conn *Conn) Connect(ctx context.Context) error {
+	if prev := conn.current(); prev != nil && !prev.IsClosed() {
+		_ = prev.Close(ctx)
+	}
+	c, err := pgx5.ConnectConfig(ctx, conn.cfg)
+	if err != nil {
+		return err
+	}
+	conn.mu.Lock()
+	conn.Conn = c
+	conn.mu.Unlock()
+	if conn.logConnect {
+		conn.logger.Debug("pgx conn: created new connection",
+			"local address", c.PgConn().Conn().LocalAddr().String(),
+			"backend PID", c.PgConn().PID(),
+		)
+	}
+	return nil
+}
+
+// Healthcheck reports closed connection, probe query is not sent,
+// as connection could be in use by its owner.
+func (conn *Conn) Healthcheck(_ context.Context) error {
+	c := conn.current()
+	if c == nil {
+		return ErrConnClosed
+	}
+	// status of connection is not synchronized with its owner,
+	// cleanup channel is closed once connection is closed
+	select {
+	case <-c.PgConn().CleanupDone():
+		return ErrConnClosed
+	default:
+		return nil
+	}
+}
+
+func (conn *Conn) current() *pgx5.Conn {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	return conn.Conn
+}
+
+func (conn *Conn) Shutdown(ctx context.Context) error {
+	c := conn.current()
+	if c == nil {
+		return nil
+	}
+	if conn.logConnect {
+		conn.logger.Debug("pgx conn: closing conn",
+			"backend PID", c.PgConn().PID(),
+		)
+	}
+	return c.Close(ctx)
+}
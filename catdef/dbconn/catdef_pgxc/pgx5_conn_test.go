@@ -0,0 +1,129 @@
+package catdefpgxc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catdb"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// fakeServer accepts connections, completes startup and waits for terminate.
+func fakeServer(t *testing.T) *net.TCPAddr {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				backend := pgproto3.NewBackend(c, c)
+				if _, err := backend.ReceiveStartupMessage(); err != nil {
+					return
+				}
+				backend.Send(&pgproto3.AuthenticationOk{})
+				backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+				if err := backend.Flush(); err != nil {
+					return
+				}
+				for {
+					if _, err := backend.Receive(); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr)
+}
+
+func newConn(t *testing.T, addr *net.TCPAddr) *Conn {
+	t.Helper()
+	cfg := catcfg.Config{}
+	cfg.ConfigDB = catdb.ConfigDB{Host: addr.IP.String(), Port: uint16(addr.Port), Name: "db", User: "user"}
+	conn, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestConnLifecycle(t *testing.T) {
+	ctx := context.Background()
+	conn := newConn(t, fakeServer(t))
+
+	if err := conn.Healthcheck(ctx); !errors.Is(err, ErrConnClosed) {
+		t.Fatalf("ErrConnClosed expected before init, got %v", err)
+	}
+	if err := conn.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown before init: %v", err)
+	}
+
+	if err := conn.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Healthcheck(ctx); err != nil {
+		t.Fatalf("healthy conn expected, got %v", err)
+	}
+
+	prev := conn.Conn
+	if err := conn.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !prev.IsClosed() {
+		t.Fatal("previous conn is not closed on reconnect")
+	}
+	if err := conn.Healthcheck(ctx); err != nil {
+		t.Fatalf("healthy conn expected after reconnect, got %v", err)
+	}
+
+	if err := conn.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Healthcheck(ctx); !errors.Is(err, ErrConnClosed) {
+		t.Fatalf("ErrConnClosed expected after shutdown, got %v", err)
+	}
+}
+
+func TestConnHealthcheckDuringConnect(t *testing.T) {
+	ctx := context.Background()
+	conn := newConn(t, fakeServer(t))
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = conn.Healthcheck(ctx)
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if err := conn.Connect(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if err := conn.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,36 +2,46 @@ package catdefpgxp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/surkovvs/gocat/catcfg"
-	"github.com/surkovvs/gocat/catlog"
+	"github.com/surkovvs/gocat/catdb"
+	"github.com/surkovvs/gocat/catmetrics"
+	"github.com/surkovvs/gocat/internal/pgx5cfg"
+	"github.com/surkovvs/gocat/internal/pgx5trace"
 
 	pgx5 "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-type (
-	Pool struct {
-		cfg *pgxpool.Config
-		*pgxpool.Pool
-	}
+var ErrNotInitialized = errors.New("pool is not initialized")
 
-	tracer struct {
-		catlog.Logger
-	}
-)
+type Pool struct {
+	cfg     *pgxpool.Config
+	health  *catdb.HealthProbe
+	latency *catmetrics.HistogramVec
+	*pgxpool.Pool
+}
 
 func New(cfg catcfg.Config) (*Pool, error) {
 	cfgPool, err := pgxpool.ParseConfig(cfg.GetDSN())
 	if err != nil {
 		return nil, fmt.Errorf("pool parse config: %w", err)
 	}
+	pgx5cfg.SetServerName(cfgPool.ConnConfig, cfg.ServerName)
+
+	latency := catmetrics.NewHistogramVec("gocat_db_query_duration_seconds",
+		"Duration of queries by sqlc-style name comment.", "query", catmetrics.DefaultBuckets)
+	if cfg.TraceEnabled() {
+		cfgPool.ConnConfig.Tracer = pgx5trace.New(cfg.Logger, cfg.ConfigTrace).
+			WithObserver(func(name string, d time.Duration) {
+				latency.Observe(name, d.Seconds())
+			})
+	}
 
 	if cfg.Logger != nil {
-		if cfg.ConfigDB.LogQueries {
-			cfgPool.ConnConfig.Tracer = tracer{cfg.Logger}
-		}
 		if cfg.ConfigDB.ConfigPool.LogConnectOperations {
 			cfgPool.BeforeConnect = func(_ context.Context, _ *pgx5.ConnConfig) error {
 				cfg.Logger.Debug("pgx pool: init new connection")
@@ -76,7 +86,9 @@ func New(cfg catcfg.Config) (*Pool, error) {
 	setIfNotNil(&cfgPool.HealthCheckPeriod, cfg.HealthCheckPeriod)
 
 	return &Pool{
-		cfg: cfgPool,
+		cfg:     cfgPool,
+		health:  catdb.NewHealthProbe(cfg.ConfigHealth),
+		latency: latency,
 	}, nil
 }
 
@@ -92,56 +104,48 @@ func (pool *Pool) Init(ctx context.Context) error {
 	return err
 }
 
-func (pool *Pool) Shutdown(_ context.Context) error {
-	pool.Pool.Close()
-	return nil
-}
-
-// queries
-
-func (tracer tracer) TraceQueryStart(
-	ctx context.Context,
-	_ *pgx5.Conn,
-	data pgx5.TraceQueryStartData,
-) context.Context {
-	tracer.Logger.Debug("Query command executing",
-		"sql", data.SQL,
-		"args", data.Args)
-
-	return ctx
-}
-
-func (tracer tracer) TraceQueryEnd(_ context.Context, _ *pgx5.Conn, _ pgx5.TraceQueryEndData) {
+// Healthcheck runs probe query, saturated pool is reported with catdb.ErrDegraded.
+func (pool *Pool) Healthcheck(ctx context.Context) error {
+	if pool.Pool == nil {
+		return ErrNotInitialized
+	}
+	stat := pool.Stat()
+	return pool.health.Check(ctx, func(ctx context.Context, query string) error {
+		_, err := pool.Exec(ctx, query)
+		return err
+	}, catdb.PoolStats{
+		Acquired:     int64(stat.AcquiredConns()),
+		Max:          int64(stat.MaxConns()),
+		WaitCount:    stat.EmptyAcquireCount(),
+		WaitDuration: stat.EmptyAcquireWaitTime(),
+	})
 }
 
-// batch
-
-func (tracer tracer) TraceBatchStart(ctx context.Context, _ *pgx5.Conn, data pgx5.TraceBatchStartData) context.Context {
-	tracer.Logger.Debug("Batch start",
-		"batch len", data.Batch.Len(),
-		"queries", data.Batch.QueuedQueries)
-	return ctx
+// Collect writes pool stats and query latencies, module is labeled by registry.
+func (pool *Pool) Collect(w *catmetrics.Writer) {
+	pool.collect(w)
 }
 
-func (tracer tracer) TraceBatchQuery(_ context.Context, _ *pgx5.Conn, data pgx5.TraceBatchQueryData) {
-	tracer.Logger.Debug(
-		"Batch query command executing",
-		"sql", data.SQL,
-		"args", data.Args,
-		"error", data.Err)
+func (pool *Pool) collect(w *catmetrics.Writer, labels ...catmetrics.Label) {
+	pool.latency.CollectWith(w, labels...)
+	if pool.Pool == nil {
+		return
+	}
+	stat := pool.Stat()
+	w.Gauge("gocat_db_pool_acquired_conns", "Connections in use.", float64(stat.AcquiredConns()), labels...)
+	w.Gauge("gocat_db_pool_idle_conns", "Idle connections.", float64(stat.IdleConns()), labels...)
+	w.Gauge("gocat_db_pool_total_conns", "Open connections.", float64(stat.TotalConns()), labels...)
+	w.Gauge("gocat_db_pool_max_conns", "Maximum of open connections.", float64(stat.MaxConns()), labels...)
+	w.Counter("gocat_db_pool_acquire_total", "Connection acquires.", float64(stat.AcquireCount()), labels...)
+	w.Counter("gocat_db_pool_acquire_duration_seconds_total", "Time spent to acquire connections.",
+		stat.AcquireDuration().Seconds(), labels...)
+	w.Counter("gocat_db_pool_canceled_acquire_total", "Acquires canceled by context.",
+		float64(stat.CanceledAcquireCount()), labels...)
+	w.Counter("gocat_db_pool_empty_acquire_total", "Acquires which waited for connection.",
+		float64(stat.EmptyAcquireCount()), labels...)
 }
 
-func (tracer tracer) TraceBatchEnd(ctx context.Context, conn *pgx5.Conn, data pgx5.TraceBatchEndData) {
+func (pool *Pool) Shutdown(_ context.Context) error {
+	pool.Pool.Close()
+	return nil
 }
-
-// func (tracer defaultTracerPGX) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context
-
-// func (tracer defaultTracerPGX) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData)
-
-// func (tracer defaultTracerPGX) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context
-
-// func (tracer defaultTracerPGX) TracePrepareEnd(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareEndData)
-
-// func (tracer defaultTracerPGX) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context
-
-// func (tracer defaultTracerPGX) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData)
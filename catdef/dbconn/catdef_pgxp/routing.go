@@ -0,0 +1,231 @@
+package catdefpgxp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catdb"
+	"github.com/surkovvs/gocat/catlog"
+	"github.com/surkovvs/gocat/catmetrics"
+)
+
+const defaultReplicaCheckPeriod = 5 * time.Second
+
+type routeKey struct{}
+
+type route int
+
+const (
+	routePrimary route = iota
+	routeReplica
+)
+
+// WithReplica marks context, so RoutingPool.Route returns replica
+// for it, use for reads which tolerate replication lag.
+func WithReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, routeReplica)
+}
+
+// WithPrimary marks context, so RoutingPool.Route returns primary for it,
+// e.g. to read own writes in code which is called with WithReplica context.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, routePrimary)
+}
+
+type replica struct {
+	*Pool
+	name    string
+	healthy atomic.Bool
+}
+
+// RoutingPool consists of primary pool and read replicas pools, initialized
+// and shut down as single module. Replicas are checked periodically, reads
+// are routed to the primary if there are no healthy replicas.
+type RoutingPool struct {
+	primary     *Pool
+	replicas    []*replica
+	logger      catlog.Logger
+	checkPeriod time.Duration
+	next        atomic.Uint32
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+func NewRouting(cfg catcfg.Config) (*RoutingPool, error) {
+	primary, err := New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("primary: %w", err)
+	}
+	rp := &RoutingPool{
+		primary:     primary,
+		logger:      cfg.Logger,
+		checkPeriod: defaultReplicaCheckPeriod,
+		stop:        make(chan struct{}),
+	}
+	setIfNotNil(&rp.checkPeriod, cfg.ReplicaCheckPeriod)
+
+	replicaDBs, err := cfg.ReplicaConfigs()
+	if err != nil {
+		return nil, err
+	}
+	for i, replicaDB := range replicaDBs {
+		replicaCfg := cfg
+		replicaCfg.ConfigDB = replicaDB
+		pool, err := New(replicaCfg)
+		if err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		var name string
+		switch replicaCfg := cfg.Replicas[i]; {
+		case replicaCfg.DSN != "":
+			name = fmt.Sprintf("replica %d", i)
+		case replicaDB.Port != 0:
+			name = fmt.Sprintf("%s:%d", replicaDB.Host, replicaDB.Port)
+		default:
+			name = replicaDB.Host // port of the primary DSN
+		}
+		rp.replicas = append(rp.replicas, &replica{Pool: pool, name: name})
+	}
+	return rp, nil
+}
+
+// Primary returns pool of primary, use it for writes.
+func (rp *RoutingPool) Primary() *Pool {
+	return rp.primary
+}
+
+// Replica returns healthy replica pool in round robin order,
+// or primary if there are no healthy replicas.
+func (rp *RoutingPool) Replica() *Pool {
+	n := len(rp.replicas)
+	if n == 0 {
+		return rp.primary
+	}
+	start := int(rp.next.Add(1))
+	for i := 0; i < n; i++ {
+		if r := rp.replicas[(start+i)%n]; r.healthy.Load() {
+			return r.Pool
+		}
+	}
+	return rp.primary
+}
+
+// Route returns pool by context hint set with WithReplica or WithPrimary,
+// primary is returned if there is no hint.
+func (rp *RoutingPool) Route(ctx context.Context) *Pool {
+	if r, _ := ctx.Value(routeKey{}).(route); r == routeReplica {
+		return rp.Replica()
+	}
+	return rp.primary
+}
+
+func (rp *RoutingPool) Init(ctx context.Context) error {
+	if err := rp.primary.Init(ctx); err != nil {
+		return fmt.Errorf("primary: %w", err)
+	}
+	for _, r := range rp.replicas {
+		if err := r.Init(ctx); err != nil {
+			return fmt.Errorf("replica %s: %w", r.name, err)
+		}
+	}
+	rp.checkReplicas(ctx)
+
+	rp.wg.Add(1)
+	go rp.checkLoop()
+	return nil
+}
+
+func (rp *RoutingPool) checkLoop() {
+	defer rp.wg.Done()
+	ticker := time.NewTicker(rp.checkPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rp.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), rp.checkPeriod)
+			rp.checkReplicas(ctx)
+			cancel()
+		}
+	}
+}
+
+func (rp *RoutingPool) checkReplicas(ctx context.Context) {
+	for _, r := range rp.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, rp.checkPeriod)
+		err := r.Ping(pingCtx)
+		cancel()
+		healthy := err == nil
+		if r.healthy.Swap(healthy) == healthy || rp.logger == nil {
+			continue
+		}
+		if healthy {
+			rp.logger.Info("pgx routing pool: replica is available", "replica", r.name)
+		} else {
+			rp.logger.Warn("pgx routing pool: replica is unavailable, reads fall back to primary",
+				"replica", r.name,
+				"error", err)
+		}
+	}
+}
+
+// Healthcheck checks primary, if all replicas are unavailable,
+// it is reported with catdb.ErrDegraded.
+func (rp *RoutingPool) Healthcheck(ctx context.Context) error {
+	if err := rp.primary.Healthcheck(ctx); err != nil {
+		return fmt.Errorf("primary: %w", err)
+	}
+	if len(rp.replicas) == 0 {
+		return nil
+	}
+	for _, r := range rp.replicas {
+		if r.healthy.Load() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: all replicas are unavailable", catdb.ErrDegraded)
+}
+
+// Collect writes metrics of primary and replicas labeled with role,
+// replicas are labeled with name and health status as well.
+func (rp *RoutingPool) Collect(w *catmetrics.Writer) {
+	rp.primary.collect(w, catmetrics.Label{Name: "role", Value: "primary"})
+	for _, r := range rp.replicas {
+		labels := []catmetrics.Label{
+			{Name: "role", Value: "replica"},
+			{Name: "replica", Value: r.name},
+		}
+		r.collect(w, labels...)
+		var healthy float64
+		if r.healthy.Load() {
+			healthy = 1
+		}
+		w.Gauge("gocat_db_replica_healthy", "Replica is available for reads.", healthy, labels...)
+	}
+}
+
+func (rp *RoutingPool) Shutdown(ctx context.Context) error {
+	select {
+	case <-rp.stop:
+	default:
+		close(rp.stop)
+	}
+	rp.wg.Wait()
+
+	var errs []error
+	for _, r := range rp.replicas {
+		if r.Pool.Pool != nil {
+			errs = append(errs, r.Shutdown(ctx))
+		}
+	}
+	if rp.primary.Pool != nil {
+		errs = append(errs, rp.primary.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
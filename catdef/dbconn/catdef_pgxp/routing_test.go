@@ -0,0 +1,113 @@
+package catdefpgxp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catdb"
+	"github.com/surkovvs/gocat/catmetrics"
+)
+
+func TestRoutingPool(t *testing.T) {
+	cfg := catcfg.Config{}
+	cfg.ConfigDB = catdb.ConfigDB{
+		Host: "primary",
+		Port: 5432,
+		Name: "db",
+		User: "user",
+		Replicas: []catdb.ConfigReplica{
+			{Host: "replica1"},
+			{Host: "replica2", Port: 6432},
+		},
+	}
+	rp, err := NewRouting(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rp.replicas) != 2 || rp.replicas[0].name != "replica1:5432" || rp.replicas[1].name != "replica2:6432" {
+		t.Fatalf("unexpected replicas: %v, %v", rp.replicas[0].name, rp.replicas[1].name)
+	}
+
+	ctx := context.Background()
+	if rp.Route(WithReplica(ctx)) != rp.Primary() {
+		t.Fatal("primary expected when replicas are unhealthy")
+	}
+
+	rp.replicas[1].healthy.Store(true)
+	for i := 0; i < 3; i++ {
+		if rp.Route(WithReplica(ctx)) != rp.replicas[1].Pool {
+			t.Fatal("healthy replica expected")
+		}
+	}
+	if rp.Route(ctx) != rp.Primary() || rp.Route(WithPrimary(WithReplica(ctx))) != rp.Primary() {
+		t.Fatal("primary expected without replica hint")
+	}
+
+	rp.replicas[0].healthy.Store(true)
+	seen := map[*Pool]bool{}
+	for i := 0; i < 4; i++ {
+		seen[rp.Replica()] = true
+	}
+	if len(seen) != 2 || seen[rp.Primary()] {
+		t.Fatal("reads are not balanced between replicas")
+	}
+}
+
+func TestPoolHealthcheckNotInitialized(t *testing.T) {
+	cfg := catcfg.Config{}
+	cfg.ConfigDB = catdb.ConfigDB{Host: "primary", Port: 5432, Name: "db", User: "user"}
+	pool, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Healthcheck(context.Background()); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("ErrNotInitialized expected, got %v", err)
+	}
+}
+
+func TestPoolCollect(t *testing.T) {
+	cfg := catcfg.Config{}
+	cfg.ConfigDB = catdb.ConfigDB{DSN: "postgres://user@primary/db"}
+	pool, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.latency.Observe("GetUser", 0.01)
+
+	reg := catmetrics.NewRegistry()
+	reg.Register(pool, catmetrics.Label{Name: "module", Value: "orders"})
+	sb := strings.Builder{}
+	if err := reg.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), `gocat_db_query_duration_seconds_count{module="orders",query="GetUser"} 1`) {
+		t.Fatalf("unexpected output:\n%s", sb.String())
+	}
+	if strings.Contains(sb.String(), "gocat_db_pool_") {
+		t.Fatalf("pool stats are not expected before init:\n%s", sb.String())
+	}
+}
+
+func TestPoolTracer(t *testing.T) {
+	cfg := catcfg.Config{}
+	cfg.ConfigDB = catdb.ConfigDB{DSN: "postgres://user@primary/db"}
+	pool, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.cfg.ConnConfig.Tracer != nil {
+		t.Fatal("tracer is not expected when tracing is disabled")
+	}
+
+	cfg.ConfigDB.ConfigTrace.Enabled = true
+	pool, err = New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.cfg.ConnConfig.Tracer == nil {
+		t.Fatal("tracer is expected when tracing is enabled")
+	}
+}
@@ -0,0 +1,46 @@
+package catdefpgxp
+
+import (
+	"context"
+
+	"github.com/surkovvs/gocat/catdb"
+
+	pgx5 "github.com/jackc/pgx/v5"
+)
+
+type txKey struct {
+	pool *Pool
+}
+
+// TxFromContext returns transaction of the pool started by WithTx.
+func (pool *Pool) TxFromContext(ctx context.Context) (pgx5.Tx, bool) {
+	tx, ok := ctx.Value(txKey{pool}).(pgx5.Tx)
+	return tx, ok
+}
+
+// WithTx runs fn in transaction, which is committed if fn returns nil and rolled
+// back otherwise. Whole transaction is retried on serialization failures and deadlocks.
+// ctx passed to fn carries transaction, so WithTx called with it starts nested
+// transaction on savepoint, nested one is not retried and ignores opts.
+func (pool *Pool) WithTx(ctx context.Context, opts catdb.TxOptions, fn func(ctx context.Context, tx pgx5.Tx) error) error {
+	if tx, ok := pool.TxFromContext(ctx); ok {
+		return pgx5.BeginFunc(ctx, tx, func(sp pgx5.Tx) error {
+			return fn(context.WithValue(ctx, txKey{pool}, sp), sp)
+		})
+	}
+
+	txOpts := pgx5.TxOptions{IsoLevel: pgx5.TxIsoLevel(opts.Isolation)}
+	if opts.ReadOnly {
+		txOpts.AccessMode = pgx5.ReadOnly
+	}
+	return catdb.RunTx(ctx, opts, func(ctx context.Context) error {
+		return pgx5.BeginTxFunc(ctx, pool.Pool, txOpts, func(tx pgx5.Tx) error {
+			return fn(context.WithValue(ctx, txKey{pool}, tx), tx)
+		})
+	})
+}
+
+// WithTx runs transaction on the primary, see Pool.WithTx.
+func (rp *RoutingPool) WithTx(ctx context.Context, opts catdb.TxOptions, fn func(ctx context.Context, tx pgx5.Tx) error) error {
+	return rp.primary.WithTx(ctx, opts, fn)
+}
@@ -0,0 +1,39 @@
+package catdefsql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catdb"
+)
+
+func TestNewPQDatabaseOptions(t *testing.T) {
+	base := catdb.ConfigDB{Host: "localhost", Port: 5432, Name: "db", User: "user"}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(cfg *catdb.ConfigDB)
+		err    error
+	}{
+		{"supported", func(cfg *catdb.ConfigDB) { cfg.SSLMode = "verify-full" }, nil},
+		{"server name", func(cfg *catdb.ConfigDB) { cfg.ServerName = "db.internal" }, catdb.ErrServerNameUnsupported},
+		{"sslmode", func(cfg *catdb.ConfigDB) { cfg.SSLMode = "prefer" }, catdb.ErrSSLModeUnsupported},
+		{"target session attrs", func(cfg *catdb.ConfigDB) { cfg.TargetSessionAttrs = "read-write" }, catdb.ErrTargetSessionAttrsUnsupported},
+		{"dsn", func(cfg *catdb.ConfigDB) {
+			cfg.DSN = "postgres://user@localhost/db"
+			cfg.SSLMode = "prefer"
+			cfg.ServerName = "db.internal"
+		}, nil},
+	} {
+		dbCfg := base
+		tc.modify(&dbCfg)
+		db, err := NewPQDatabase(catcfg.Config{ConfigDB: dbCfg})
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.err, err)
+		}
+		if db != nil {
+			_ = db.Close()
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"database/sql"
 
 	"github.com/surkovvs/gocat/catcfg"
+	"github.com/surkovvs/gocat/catdb"
 	"github.com/surkovvs/gocat/catlog"
+	"github.com/surkovvs/gocat/catmetrics"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -14,9 +16,13 @@ import (
 
 type Database struct {
 	*sqlx.DB
+	health *catdb.HealthProbe
 }
 
 func InitSQLx(cfg catcfg.Config) (*Database, error) {
+	if err := cfg.CheckPQOptions(); err != nil {
+		return nil, err
+	}
 	db, err := sql.Open("postgres", cfg.ConfigDB.GetDSN())
 	if err != nil {
 		return nil, err
@@ -26,13 +32,35 @@ func InitSQLx(cfg catcfg.Config) (*Database, error) {
 		db = sqldblogger.OpenDriver(cfg.ConfigDB.GetDSN(), db.Driver(), logAdapter{cfg.Logger})
 	}
 
-	return &Database{sqlx.NewDb(db, "postgres")}, nil
+	return &Database{
+		DB:     sqlx.NewDb(db, "postgres"),
+		health: catdb.NewHealthProbe(cfg.ConfigHealth),
+	}, nil
 }
 
 func (db *Database) Init(ctx context.Context) error {
 	return db.PingContext(ctx)
 }
 
+// Healthcheck runs probe query, saturated pool is reported with catdb.ErrDegraded.
+func (db *Database) Healthcheck(ctx context.Context) error {
+	stats := db.Stats()
+	return db.health.Check(ctx, func(ctx context.Context, query string) error {
+		_, err := db.ExecContext(ctx, query)
+		return err
+	}, catdb.PoolStats{
+		Acquired:     int64(stats.InUse),
+		Max:          int64(stats.MaxOpenConnections),
+		WaitCount:    stats.WaitCount,
+		WaitDuration: stats.WaitDuration,
+	})
+}
+
+// Collect writes pool stats, module is labeled by registry.
+func (db *Database) Collect(w *catmetrics.Writer) {
+	catdb.CollectDBStats(w, db.Stats())
+}
+
 func (db *Database) Shutdown(_ context.Context) error {
 	return db.Close()
 }
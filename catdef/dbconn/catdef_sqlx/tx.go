@@ -0,0 +1,86 @@
+package sqlxconnect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/surkovvs/gocat/catdb"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type txKey struct {
+	db *Database
+}
+
+type txState struct {
+	tx    *sqlx.Tx
+	depth int
+}
+
+// TxFromContext returns transaction of the database started by WithTx.
+func (db *Database) TxFromContext(ctx context.Context) (*sqlx.Tx, bool) {
+	st, ok := ctx.Value(txKey{db}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	return st.tx, true
+}
+
+// WithTx runs fn in transaction, which is committed if fn returns nil and rolled
+// back otherwise. Whole transaction is retried on serialization failures and deadlocks.
+// ctx passed to fn carries transaction, so WithTx called with it starts nested
+// transaction on savepoint, nested one is not retried and ignores opts.
+func (db *Database) WithTx(ctx context.Context, opts catdb.TxOptions, fn func(ctx context.Context, tx *sqlx.Tx) error) error {
+	if st, ok := ctx.Value(txKey{db}).(*txState); ok {
+		return st.savepoint(ctx, fn)
+	}
+
+	return catdb.RunTx(ctx, opts, func(ctx context.Context) error {
+		tx, err := db.BeginTxx(ctx, &sql.TxOptions{
+			Isolation: opts.Isolation.SQL(),
+			ReadOnly:  opts.ReadOnly,
+		})
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		committed := false
+		defer func() {
+			if !committed {
+				_ = tx.Rollback()
+			}
+		}()
+
+		if err := fn(context.WithValue(ctx, txKey{db}, &txState{tx: tx}), tx); err != nil {
+			return err
+		}
+		committed = true
+		return tx.Commit()
+	})
+}
+
+func (st *txState) savepoint(ctx context.Context, fn func(ctx context.Context, tx *sqlx.Tx) error) error {
+	st.depth++
+	defer func() { st.depth-- }()
+	name := fmt.Sprintf("sp_%d", st.depth)
+
+	if _, err := st.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("savepoint: %w", err)
+	}
+	released := false
+	defer func() {
+		if !released {
+			_, _ = st.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		}
+	}()
+
+	if err := fn(ctx, st.tx); err != nil {
+		return err
+	}
+	released = true
+	if _, err := st.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
@@ -10,13 +10,27 @@ import (
 )
 
 func NewZapDefault(cfg catlog.Configurer) *zap.Logger {
-	var encoder zapcore.Encoder
+	return zap.New(zapcore.NewCore(newEncoder(cfg), os.Stdout, zapLevel(cfg)))
+}
+
+// NewZapWatched creates logger which level follows configuration changes,
+// encoder is defined once by initial configuration.
+func NewZapWatched(cfg catlog.Configurer, notifier catlog.ConfigNotifier) *zap.Logger {
+	lvl := zap.NewAtomicLevelAt(zapLevel(cfg))
+	notifier.NotifyLogConfig(func(cfg catlog.Configurer) {
+		lvl.SetLevel(zapLevel(cfg))
+	})
+	return zap.New(zapcore.NewCore(newEncoder(cfg), os.Stdout, lvl))
+}
+
+func newEncoder(cfg catlog.Configurer) zapcore.Encoder {
 	if cfg.IsJSONEncoder() {
-		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
-	} else {
-		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+		return zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
 	}
+	return zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+}
 
+func zapLevel(cfg catlog.Configurer) zapcore.Level {
 	zapLvl := zapcore.WarnLevel
 	if lvl := cfg.GetLogLvl(); lvl != nil {
 		switch *lvl {
@@ -30,6 +44,5 @@ func NewZapDefault(cfg catlog.Configurer) *zap.Logger {
 			zapLvl = zapcore.ErrorLevel
 		}
 	}
-
-	return zap.New(zapcore.NewCore(encoder, os.Stdout, zapLvl))
+	return zapLvl
 }
@@ -2,6 +2,7 @@ package catdefzerolog
 
 import (
 	"os"
+	"sync/atomic"
 
 	"github.com/surkovvs/gocat/catlog"
 
@@ -9,21 +10,49 @@ import (
 )
 
 func NewZerologDefault(cfg catlog.Configurer) *zerolog.Logger {
+	zerolog.SetGlobalLevel(zerologLevel(cfg))
+
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
+	return &logger
+}
+
+// NewZerologWatched creates logger, which level follows configuration changes,
+// level is kept by the logger instance, zerolog global level is not changed.
+func NewZerologWatched(cfg catlog.Configurer, notifier catlog.ConfigNotifier) *zerolog.Logger {
+	hook := &levelHook{}
+	hook.level.Store(int32(zerologLevel(cfg)))
+	notifier.NotifyLogConfig(func(cfg catlog.Configurer) {
+		hook.level.Store(int32(zerologLevel(cfg)))
+	})
+
+	logger := zerolog.New(os.Stdout).Hook(hook).With().Timestamp().Logger()
+	return &logger
+}
+
+// levelHook discards events below level, which could be changed concurrently
+// with logging, unlike level of zerolog.Logger.
+type levelHook struct {
+	level atomic.Int32
+}
+
+func (h *levelHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	if level < zerolog.Level(h.level.Load()) {
+		e.Discard()
+	}
+}
+
+func zerologLevel(cfg catlog.Configurer) zerolog.Level {
 	if lvl := cfg.GetLogLvl(); lvl != nil {
 		switch *lvl {
 		case catlog.LevelDebug:
-			zerolog.SetGlobalLevel(zerolog.DebugLevel)
+			return zerolog.DebugLevel
 		case catlog.LevelInfo:
-			zerolog.SetGlobalLevel(zerolog.InfoLevel)
+			return zerolog.InfoLevel
 		case catlog.LevelWarn:
-			zerolog.SetGlobalLevel(zerolog.WarnLevel)
+			return zerolog.WarnLevel
 		case catlog.LevelError:
-			zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+			return zerolog.ErrorLevel
 		}
-	} else {
-		zerolog.SetGlobalLevel(zerolog.WarnLevel)
 	}
-
-	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
-	return &logger
+	return zerolog.WarnLevel
 }
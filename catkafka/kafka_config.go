@@ -1,3 +1,141 @@
 package catkafka
 
-type ConfigKafka struct{}
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type (
+	Role          string
+	PartitionHash string
+)
+
+const (
+	RoleProducer Role = "producer"
+	RoleConsumer Role = "consumer"
+
+	PartitionHashCRC32  PartitionHash = "crc32"
+	PartitionHashXXHash PartitionHash = "xxhash"
+	PartitionHashRR     PartitionHash = "rr"
+)
+
+// ConfigRetry is used by sync producer only, async producer sends undelivered
+// messages to DLQ at once, its retries are up to librdkafka properties,
+// e.g. message.send.max.retries.
+type ConfigRetry struct {
+	Attempts   int           `mapstructure:"attempts"` // 0 - no retries
+	Backoff    time.Duration `mapstructure:"backoff"`
+	MaxBackoff time.Duration `mapstructure:"max.backoff"`
+}
+
+type ConfigDLQ struct {
+	Topic string `mapstructure:"topic"` // empty - DLQ is disabled
+}
+
+// ConfigKafka is config of the kafka tag. gocat settings are typed fields,
+// librdkafka properties could be set in librdkafka section or directly in the tag:
+//
+//	Kafka:
+//	  produser:
+//	    role: 'producer'
+//	    topic: 'my_topic'
+//	    bootstrap.servers: '127.0.0.1:9092' # passthrough
+//	    librdkafka:
+//	      linger.ms: 0
+type ConfigKafka struct {
+	Role           Role          `mapstructure:"role"`
+	Topic          string        `mapstructure:"topic"`
+	Topics         []string      `mapstructure:"topics"`
+	Sync           bool          `mapstructure:"sync"`
+	PartitionHash  PartitionHash `mapstructure:"partition.hash"`
+	FlushTimeoutMs int           `mapstructure:"flush.timeout.ms"`
+	Retry          ConfigRetry   `mapstructure:"retry"`
+	DLQ            ConfigDLQ     `mapstructure:"dlq"`
+
+	Librdkafka  map[string]any `mapstructure:"librdkafka"`
+	Passthrough map[string]any `mapstructure:",remain"`
+}
+
+// GetTopics returns topic and topics joined, topic goes first.
+func (cfg ConfigKafka) GetTopics() []string {
+	var topics []string
+	if cfg.Topic != "" {
+		topics = append(topics, cfg.Topic)
+	}
+	for _, topic := range cfg.Topics {
+		if topic != cfg.Topic {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// ConfigMap returns new librdkafka config, values of librdkafka section win over
+// values set directly in the tag. Strings with bools and ints are converted for go.*
+// properties only, as they are checked by types in confluent client, librdkafka
+// properties are passed as is, so e.g. password "0123" is kept string.
+func (cfg ConfigKafka) ConfigMap() kafka.ConfigMap {
+	cm := make(kafka.ConfigMap, len(cfg.Passthrough)+len(cfg.Librdkafka))
+	for _, props := range []map[string]any{cfg.Passthrough, cfg.Librdkafka} {
+		for key, val := range props {
+			cm[key] = normalizeValue(key, val)
+		}
+	}
+	return cm
+}
+
+// Property returns librdkafka property value
+func (cfg ConfigKafka) Property(key string) (any, bool) {
+	if val, ok := cfg.Librdkafka[key]; ok {
+		return normalizeValue(key, val), true
+	}
+	val, ok := cfg.Passthrough[key]
+	return normalizeValue(key, val), ok
+}
+
+func normalizeValue(key string, val any) any {
+	switch typed := val.(type) {
+	case string:
+		if !strings.HasPrefix(key, "go.") {
+			return val
+		}
+		switch {
+		case strings.EqualFold(typed, "true"):
+			return true
+		case strings.EqualFold(typed, "false"):
+			return false
+		}
+		if i, err := strconv.Atoi(typed); err == nil && strconv.Itoa(i) == typed {
+			return i
+		}
+	case int64:
+		return int(typed)
+	case uint64:
+		return int(typed)
+	case float64:
+		if typed == math.Trunc(typed) {
+			return int(typed)
+		}
+	}
+	return val
+}
+
+// Delay returns backoff before retry attempt (starting from 1), doubled on every
+// attempt and limited by MaxBackoff if it set, it is not doubled past overflow.
+func (r ConfigRetry) Delay(attempt int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < attempt && delay > 0 && delay <= math.MaxInt64/2; i++ {
+		delay *= 2
+		if r.MaxBackoff > 0 && delay >= r.MaxBackoff {
+			break
+		}
+	}
+	if r.MaxBackoff > 0 && delay > r.MaxBackoff {
+		delay = r.MaxBackoff
+	}
+	return delay
+}
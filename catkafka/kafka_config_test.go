@@ -0,0 +1,27 @@
+package catkafka
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	capped := ConfigRetry{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, expected := range map[int]time.Duration{
+		1:   time.Second,
+		3:   4 * time.Second,
+		4:   5 * time.Second,
+		100: 5 * time.Second,
+	} {
+		if delay := capped.Delay(attempt); delay != expected {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, expected, delay)
+		}
+	}
+
+	uncapped := ConfigRetry{Backoff: time.Second}
+	for _, attempt := range []int{10, 100, 1000} {
+		if delay := uncapped.Delay(attempt); delay <= 0 {
+			t.Errorf("attempt %d: expected positive delay, got %v", attempt, delay)
+		}
+	}
+}
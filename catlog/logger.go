@@ -60,3 +60,9 @@ func (c ConfigLog) GetLogLvl() *Level {
 func (c ConfigLog) IsJSONEncoder() bool {
 	return !c.ConsoleEncoder
 }
+
+// ConfigNotifier delivers logger configuration on its changes,
+// e.g. on config reload, cancel stops notifications.
+type ConfigNotifier interface {
+	NotifyLogConfig(fn func(Configurer)) (cancel func())
+}
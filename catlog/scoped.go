@@ -0,0 +1,47 @@
+package catlog
+
+type scopedLogger struct {
+	logger Logger
+	fields []any
+}
+
+// With returns logger which adds fields to every record.
+func With(logger Logger, fields ...any) Logger {
+	if sl, ok := logger.(scopedLogger); ok {
+		return scopedLogger{
+			logger: sl.logger,
+			fields: append(append([]any{}, sl.fields...), fields...),
+		}
+	}
+	return scopedLogger{
+		logger: logger,
+		fields: fields,
+	}
+}
+
+func (sl scopedLogger) Debug(msg string, args ...any) {
+	sl.logger.Debug(msg, append(append([]any{}, sl.fields...), args...)...)
+}
+
+func (sl scopedLogger) Info(msg string, args ...any) {
+	sl.logger.Info(msg, append(append([]any{}, sl.fields...), args...)...)
+}
+
+func (sl scopedLogger) Warn(msg string, args ...any) {
+	sl.logger.Warn(msg, append(append([]any{}, sl.fields...), args...)...)
+}
+
+func (sl scopedLogger) Error(msg string, args ...any) {
+	sl.logger.Error(msg, append(append([]any{}, sl.fields...), args...)...)
+}
+
+type nopLogger struct{}
+
+func Nop() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
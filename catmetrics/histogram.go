@@ -0,0 +1,111 @@
+package catmetrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds.
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64 // per bucket, not cumulative
+	Count  uint64
+	Sum    float64
+}
+
+type Histogram struct {
+	mu   *sync.Mutex
+	snap HistogramSnapshot
+}
+
+func NewHistogram(bounds []float64) *Histogram {
+	bounds = append([]float64{}, bounds...)
+	sort.Float64s(bounds)
+	return &Histogram{
+		mu: &sync.Mutex{},
+		snap: HistogramSnapshot{
+			Bounds: bounds,
+			Counts: make([]uint64, len(bounds)),
+		},
+	}
+}
+
+func (h *Histogram) Observe(val float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i := sort.SearchFloat64s(h.snap.Bounds, val); i < len(h.snap.Bounds) {
+		h.snap.Counts[i]++
+	}
+	h.snap.Count++
+	h.snap.Sum += val
+}
+
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	snap := h.snap
+	snap.Counts = append([]uint64{}, h.snap.Counts...)
+	return snap
+}
+
+// HistogramVec is set of histograms partitioned by value of single label.
+type HistogramVec struct {
+	name   string
+	help   string
+	label  string
+	bounds []float64
+
+	mu    *sync.RWMutex
+	hists map[string]*Histogram
+}
+
+func NewHistogramVec(name, help, label string, bounds []float64) *HistogramVec {
+	return &HistogramVec{
+		name:   name,
+		help:   help,
+		label:  label,
+		bounds: bounds,
+		mu:     &sync.RWMutex{},
+		hists:  make(map[string]*Histogram),
+	}
+}
+
+func (hv *HistogramVec) Observe(labelValue string, val float64) {
+	hv.mu.RLock()
+	h, ok := hv.hists[labelValue]
+	hv.mu.RUnlock()
+	if !ok {
+		hv.mu.Lock()
+		if h, ok = hv.hists[labelValue]; !ok {
+			h = NewHistogram(hv.bounds)
+			hv.hists[labelValue] = h
+		}
+		hv.mu.Unlock()
+	}
+	h.Observe(val)
+}
+
+// CollectWith writes histograms with extra labels.
+func (hv *HistogramVec) CollectWith(w *Writer, labels ...Label) {
+	hv.mu.RLock()
+	values := make([]string, 0, len(hv.hists))
+	for value := range hv.hists {
+		values = append(values, value)
+	}
+	hv.mu.RUnlock()
+	sort.Strings(values)
+
+	for _, value := range values {
+		hv.mu.RLock()
+		h := hv.hists[value]
+		hv.mu.RUnlock()
+		w.Histogram(hv.name, hv.help, h.Snapshot(),
+			append(labels[:len(labels):len(labels)], Label{Name: hv.label, Value: value})...)
+	}
+}
+
+func (hv *HistogramVec) Collect(w *Writer) {
+	hv.CollectWith(w)
+}
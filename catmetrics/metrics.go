@@ -0,0 +1,165 @@
+// Package catmetrics exposes metrics of gocat modules in Prometheus text format.
+package catmetrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Collector is implemented by modules, which expose metrics, modules added
+// to catapp application are registered in its registry automatically,
+// labeled with names of group and module.
+type Collector interface {
+	Collect(w *Writer)
+}
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+type registered struct {
+	collector Collector
+	labels    []Label
+}
+
+type Registry struct {
+	mu         *sync.Mutex
+	collectors []registered
+}
+
+func NewRegistry() *Registry {
+	return &Registry{mu: &sync.Mutex{}}
+}
+
+// Register adds collector, labels are added to all its samples,
+// catapp labels modules with names of module and group.
+func (r *Registry) Register(c Collector, labels ...Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, registered{collector: c, labels: labels})
+}
+
+// WriteText writes metrics of all collectors in Prometheus text format.
+func (r *Registry) WriteText(out io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]registered{}, r.collectors...)
+	r.mu.Unlock()
+
+	families := make(map[string]*family)
+	for _, c := range collectors {
+		c.collector.Collect(&Writer{families: families, labels: c.labels})
+	}
+	return (&Writer{families: families}).flush(out)
+}
+
+// Handler serves metrics for scraping.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(rw); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+type family struct {
+	help  string
+	typ   string
+	lines []string
+}
+
+// Writer groups samples by metric name, so collectors could write
+// samples of the same metric with different labels.
+type Writer struct {
+	families map[string]*family
+	labels   []Label // of registered collector
+}
+
+func (w *Writer) Gauge(name, help string, val float64, labels ...Label) {
+	w.sample(name, help, "gauge", name, val, labels)
+}
+
+func (w *Writer) Counter(name, help string, val float64, labels ...Label) {
+	w.sample(name, help, "counter", name, val, labels)
+}
+
+// Histogram writes cumulative buckets, sum and count of histogram snapshot.
+func (w *Writer) Histogram(name, help string, snap HistogramSnapshot, labels ...Label) {
+	var cumulative uint64
+	for i, bound := range snap.Bounds {
+		cumulative += snap.Counts[i]
+		le := Label{Name: "le", Value: formatFloat(bound)}
+		w.sample(name, help, "histogram", name+"_bucket", float64(cumulative), append(labels[:len(labels):len(labels)], le))
+	}
+	inf := Label{Name: "le", Value: "+Inf"}
+	w.sample(name, help, "histogram", name+"_bucket", float64(snap.Count), append(labels[:len(labels):len(labels)], inf))
+	w.sample(name, help, "histogram", name+"_sum", snap.Sum, labels)
+	w.sample(name, help, "histogram", name+"_count", float64(snap.Count), labels)
+}
+
+func (w *Writer) sample(name, help, typ, series string, val float64, labels []Label) {
+	fam, ok := w.families[name]
+	if !ok {
+		fam = &family{help: help, typ: typ}
+		w.families[name] = fam
+	}
+	if len(w.labels) != 0 {
+		labels = append(w.labels[:len(w.labels):len(w.labels)], labels...)
+	}
+	fam.lines = append(fam.lines, series+formatLabels(labels)+" "+formatFloat(val))
+}
+
+func (w *Writer) flush(out io.Writer) error {
+	names := make([]string, 0, len(w.families))
+	for name := range w.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sb := strings.Builder{}
+	for _, name := range names {
+		fam := w.families[name]
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(fam.help), name, fam.typ)
+		for _, line := range fam.lines {
+			sb.WriteString(line + "\n")
+		}
+	}
+	_, err := io.WriteString(out, sb.String())
+	return err
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Name + `="` + escapeLabel(l.Value) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(val float64) string {
+	switch {
+	case math.IsInf(val, 1):
+		return "+Inf"
+	case math.IsInf(val, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,69 @@
+package catmetrics
+
+import (
+	"strings"
+	"testing"
+)
+
+type collectorFunc func(w *Writer)
+
+func (f collectorFunc) Collect(w *Writer) { f(w) }
+
+func TestWriteText(t *testing.T) {
+	hv := NewHistogramVec("query_seconds", "Query duration.", "query", []float64{0.1, 1})
+	hv.Observe("GetUser", 0.05)
+	hv.Observe("GetUser", 0.5)
+	hv.Observe("GetUser", 5)
+
+	reg := NewRegistry()
+	reg.Register(collectorFunc(func(w *Writer) {
+		w.Gauge("conns", "Open connections.", 2, Label{Name: "db", Value: "a"})
+	}))
+	reg.Register(collectorFunc(func(w *Writer) {
+		w.Gauge("conns", "Open connections.", 3, Label{Name: "db", Value: `b"`})
+	}))
+	reg.Register(hv)
+
+	sb := strings.Builder{}
+	if err := reg.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP conns Open connections.
+# TYPE conns gauge
+conns{db="a"} 2
+conns{db="b\""} 3
+# HELP query_seconds Query duration.
+# TYPE query_seconds histogram
+query_seconds_bucket{query="GetUser",le="0.1"} 1
+query_seconds_bucket{query="GetUser",le="1"} 2
+query_seconds_bucket{query="GetUser",le="+Inf"} 3
+query_seconds_sum{query="GetUser"} 5.55
+query_seconds_count{query="GetUser"} 3
+`
+	if sb.String() != expected {
+		t.Fatalf("unexpected output:\n%s", sb.String())
+	}
+}
+
+func TestRegisterLabels(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(collectorFunc(func(w *Writer) {
+		w.Gauge("conns", "Open connections.", 2, Label{Name: "role", Value: "primary"})
+	}), Label{Name: "module", Value: "orders"})
+	reg.Register(collectorFunc(func(w *Writer) {
+		w.Gauge("conns", "Open connections.", 3)
+	}), Label{Name: "module", Value: "users"})
+
+	sb := strings.Builder{}
+	if err := reg.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP conns Open connections.
+# TYPE conns gauge
+conns{module="orders",role="primary"} 2
+conns{module="users"} 3
+`
+	if sb.String() != expected {
+		t.Fatalf("unexpected output:\n%s", sb.String())
+	}
+}
@@ -0,0 +1,22 @@
+// Package pgx5cfg contains pgx v5 connection config helpers
+// shared by pgx5 connectors.
+package pgx5cfg
+
+import (
+	pgx5 "github.com/jackc/pgx/v5"
+)
+
+// SetServerName overrides host name used for verification of server certificate
+func SetServerName(cfg *pgx5.ConnConfig, name string) {
+	if name == "" {
+		return
+	}
+	if cfg.TLSConfig != nil {
+		cfg.TLSConfig.ServerName = name
+	}
+	for _, fb := range cfg.Fallbacks {
+		if fb.TLSConfig != nil {
+			fb.TLSConfig.ServerName = name
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package pgx5trace contains pgx v5 query tracer shared by pgx5 connectors.
+package pgx5trace
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/surkovvs/gocat/catdb"
+	"github.com/surkovvs/gocat/catlog"
+
+	pgx5 "github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultMaxArgLen = 64
+	redactedArg      = "******"
+)
+
+var queryName = regexp.MustCompile(`^\s*--\s*name:\s*(\w+)`)
+
+// QueryName returns name of query from sqlc style comment "-- name: GetUser :one",
+// or "unnamed" if there is no such comment.
+func QueryName(sql string) string {
+	if match := queryName.FindStringSubmatch(sql); match != nil {
+		return match[1]
+	}
+	return "unnamed"
+}
+
+// Tracer logs queries, batches, copies, prepares and connects of pgx5 conn.
+// Failed queries are logged with Error, slow ones with Warn, other ones with
+// Debug according to sample rate.
+type Tracer struct {
+	logger  catlog.Logger // nil disables logging
+	cfg     catdb.ConfigTrace
+	observe func(name string, duration time.Duration)
+}
+
+type traceKey struct{}
+
+type traceData struct {
+	start time.Time
+	sql   string
+	args  []any
+	extra any // batch len, copy columns, statement name or port
+}
+
+func New(logger catlog.Logger, cfg catdb.ConfigTrace) *Tracer {
+	if cfg.SampleRate == 0 {
+		cfg.SampleRate = 1
+	}
+	if cfg.MaxArgLen == 0 {
+		cfg.MaxArgLen = defaultMaxArgLen
+	}
+	return &Tracer{
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// WithObserver sets func, which receives duration of each query by query name.
+func (tracer *Tracer) WithObserver(observe func(name string, duration time.Duration)) *Tracer {
+	tracer.observe = observe
+	return tracer
+}
+
+func (tracer *Tracer) start(ctx context.Context, sql string, args []any, extra any) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceData{
+		start: time.Now(),
+		sql:   sql,
+		args:  args,
+		extra: extra,
+	})
+}
+
+func started(ctx context.Context) (traceData, time.Duration) {
+	data, ok := ctx.Value(traceKey{}).(traceData)
+	if !ok {
+		return data, 0
+	}
+	return data, time.Since(data.start)
+}
+
+// log logs operation finished with err in duration
+func (tracer *Tracer) log(msg string, duration time.Duration, err error, fields ...any) {
+	if tracer.logger == nil {
+		return
+	}
+	fields = append(fields, "duration", duration)
+	switch {
+	case err != nil:
+		tracer.logger.Error(msg+" failed", append(fields, "error", err)...)
+	case tracer.cfg.SlowQuery != nil && duration >= *tracer.cfg.SlowQuery:
+		tracer.logger.Warn(msg+" is slow", append(fields, "threshold", *tracer.cfg.SlowQuery)...)
+	case tracer.cfg.SampleRate >= 1 || rand.Float64() < tracer.cfg.SampleRate:
+		tracer.logger.Debug(msg+" done", fields...)
+	}
+}
+
+func (tracer *Tracer) sql(sql string) string {
+	return truncate(sql, tracer.cfg.MaxSQLLen)
+}
+
+func (tracer *Tracer) args(args []any) []string {
+	res := make([]string, len(args))
+	for i, arg := range args {
+		b, isBytes := arg.([]byte)
+		switch {
+		case tracer.cfg.RedactArgs:
+			res[i] = redactedArg
+		case isBytes:
+			res[i] = fmt.Sprintf("[%d bytes]", len(b))
+		default:
+			res[i] = truncate(fmt.Sprint(arg), tracer.cfg.MaxArgLen)
+		}
+	}
+	return res
+}
+
+func truncate(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(%d bytes)", s[:cut], len(s))
+}
+
+// queries
+
+func (tracer *Tracer) TraceQueryStart(ctx context.Context, _ *pgx5.Conn, data pgx5.TraceQueryStartData) context.Context {
+	return tracer.start(ctx, data.SQL, data.Args, nil)
+}
+
+func (tracer *Tracer) TraceQueryEnd(ctx context.Context, conn *pgx5.Conn, data pgx5.TraceQueryEndData) {
+	query, duration := started(ctx)
+	if tracer.observe != nil {
+		tracer.observe(QueryName(query.sql), duration)
+	}
+	if tracer.logger == nil {
+		return
+	}
+	tracer.log("Query", duration, data.Err,
+		"sql", tracer.sql(query.sql),
+		"args", tracer.args(query.args),
+		"rows", data.CommandTag.RowsAffected(),
+		"backend PID", conn.PgConn().PID())
+}
+
+// batch
+
+func (tracer *Tracer) TraceBatchStart(ctx context.Context, _ *pgx5.Conn, data pgx5.TraceBatchStartData) context.Context {
+	return tracer.start(ctx, "", nil, data.Batch.Len())
+}
+
+func (tracer *Tracer) TraceBatchQuery(_ context.Context, _ *pgx5.Conn, data pgx5.TraceBatchQueryData) {
+	if tracer.logger == nil {
+		return
+	}
+	if data.Err != nil {
+		tracer.logger.Error("Batch query failed",
+			"sql", tracer.sql(data.SQL),
+			"args", tracer.args(data.Args),
+			"error", data.Err)
+		return
+	}
+	if tracer.cfg.SampleRate >= 1 || rand.Float64() < tracer.cfg.SampleRate {
+		tracer.logger.Debug("Batch query done",
+			"sql", tracer.sql(data.SQL),
+			"args", tracer.args(data.Args),
+			"rows", data.CommandTag.RowsAffected())
+	}
+}
+
+func (tracer *Tracer) TraceBatchEnd(ctx context.Context, _ *pgx5.Conn, data pgx5.TraceBatchEndData) {
+	batch, duration := started(ctx)
+	tracer.log("Batch", duration, data.Err,
+		"batch len", batch.extra)
+}
+
+// copy
+
+func (tracer *Tracer) TraceCopyFromStart(ctx context.Context, _ *pgx5.Conn, data pgx5.TraceCopyFromStartData) context.Context {
+	return tracer.start(ctx, data.TableName.Sanitize(), nil, data.ColumnNames)
+}
+
+func (tracer *Tracer) TraceCopyFromEnd(ctx context.Context, _ *pgx5.Conn, data pgx5.TraceCopyFromEndData) {
+	cp, duration := started(ctx)
+	tracer.log("Copy from", duration, data.Err,
+		"table", cp.sql,
+		"columns", cp.extra,
+		"rows", data.CommandTag.RowsAffected())
+}
+
+// prepare
+
+func (tracer *Tracer) TracePrepareStart(ctx context.Context, _ *pgx5.Conn, data pgx5.TracePrepareStartData) context.Context {
+	return tracer.start(ctx, data.SQL, nil, data.Name)
+}
+
+func (tracer *Tracer) TracePrepareEnd(ctx context.Context, _ *pgx5.Conn, data pgx5.TracePrepareEndData) {
+	if !tracer.cfg.LogPrepare && data.Err == nil {
+		return
+	}
+	prep, duration := started(ctx)
+	tracer.log("Prepare", duration, data.Err,
+		"name", prep.extra,
+		"sql", tracer.sql(prep.sql),
+		"already prepared", data.AlreadyPrepared)
+}
+
+// connect
+
+func (tracer *Tracer) TraceConnectStart(ctx context.Context, data pgx5.TraceConnectStartData) context.Context {
+	return tracer.start(ctx, data.ConnConfig.Host, nil, data.ConnConfig.Port)
+}
+
+func (tracer *Tracer) TraceConnectEnd(ctx context.Context, data pgx5.TraceConnectEndData) {
+	if !tracer.cfg.LogConnect && data.Err == nil {
+		return
+	}
+	connect, duration := started(ctx)
+	fields := []any{"host", connect.sql, "port", connect.extra}
+	if data.Conn != nil {
+		fields = append(fields, "backend PID", data.Conn.PgConn().PID())
+	}
+	tracer.log("Connect", duration, data.Err, fields...)
+}
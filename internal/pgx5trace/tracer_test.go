@@ -0,0 +1,89 @@
+package pgx5trace
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surkovvs/gocat/catdb"
+
+	pgx5 "github.com/jackc/pgx/v5"
+)
+
+type record struct {
+	level string
+	msg   string
+}
+
+type recLogger struct {
+	records []record
+}
+
+func (l *recLogger) Debug(msg string, _ ...any) { l.records = append(l.records, record{"debug", msg}) }
+func (l *recLogger) Info(msg string, _ ...any)  { l.records = append(l.records, record{"info", msg}) }
+func (l *recLogger) Warn(msg string, _ ...any)  { l.records = append(l.records, record{"warn", msg}) }
+func (l *recLogger) Error(msg string, _ ...any) { l.records = append(l.records, record{"error", msg}) }
+
+func TestTracerLevels(t *testing.T) {
+	slow := time.Millisecond
+	logger := &recLogger{}
+	tracer := New(logger, catdb.ConfigTrace{SlowQuery: &slow})
+
+	tracer.log("Query", 0, nil)
+	tracer.log("Query", 2*time.Millisecond, nil)
+	tracer.log("Query", 0, errors.New("boom"))
+	tracer.TraceBatchEnd(context.Background(), nil, pgx5.TraceBatchEndData{}) // without start
+
+	want := []record{
+		{"debug", "Query done"},
+		{"warn", "Query is slow"},
+		{"error", "Query failed"},
+		{"debug", "Batch done"},
+	}
+	if len(logger.records) != len(want) {
+		t.Fatalf("got %v", logger.records)
+	}
+	for i := range want {
+		if logger.records[i] != want[i] {
+			t.Fatalf("record %d: got %v, want %v", i, logger.records[i], want[i])
+		}
+	}
+
+	sampled := New(&recLogger{}, catdb.ConfigTrace{SampleRate: 0.000001})
+	for i := 0; i < 100; i++ {
+		sampled.log("Query", 0, nil)
+	}
+	if n := len(sampled.logger.(*recLogger).records); n > 5 {
+		t.Fatalf("sampling is not applied: %d records", n)
+	}
+}
+
+func TestTracerArgs(t *testing.T) {
+	tracer := New(nil, catdb.ConfigTrace{MaxArgLen: 8, MaxSQLLen: 10})
+	args := tracer.args([]any{"short", strings.Repeat("x", 20), []byte("secret"), 42})
+	if args[0] != "short" || args[1] != "xxxxxxxx...(20 bytes)" || args[2] != "[6 bytes]" || args[3] != "42" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+	if sql := tracer.sql("SELECT * FROM users"); sql != "SELECT * F...(19 bytes)" {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+
+	redacting := New(nil, catdb.ConfigTrace{RedactArgs: true})
+	if args := redacting.args([]any{"secret"}); args[0] != redactedArg {
+		t.Fatalf("args are not redacted: %v", args)
+	}
+}
+
+func TestQueryName(t *testing.T) {
+	for sql, want := range map[string]string{
+		"-- name: GetUser :one\nSELECT 1": "GetUser",
+		"  --name:ListOrders\nSELECT 1":   "ListOrders",
+		"SELECT 1 -- name: Other":         "unnamed",
+	} {
+		if got := QueryName(sql); got != want {
+			t.Fatalf("%q: got %s, want %s", sql, got, want)
+		}
+	}
+}